@@ -0,0 +1,95 @@
+// Command flagusage checks the flag names referenced by toggle calls in the
+// given packages. It reports names which aren't normalized, and with the
+// -server flag the ones missing on the toggle server. With -unreferenced, it
+// also lists the server flags which none of the packages reference, such as
+// when run on all the packages of a repository:
+//
+//	flagusage -server http://toggles:8080 -service checkout -unreferenced ./...
+//
+// It exits with status 3 if anything was reported.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/globusdigital/feature-toggles/analysis/flagusage"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/checker"
+	"golang.org/x/tools/go/packages"
+)
+
+var unreferenced = flag.Bool("unreferenced", false, "list the server flags which aren't referenced by the packages. Requires -server")
+
+func main() {
+	flagusage.Analyzer.Flags.VisitAll(func(f *flag.Flag) {
+		flag.Var(f.Value, f.Name, f.Usage)
+	})
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] packages...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	server := flag.Lookup("server").Value.String()
+	if flag.NArg() == 0 || *unreferenced && server == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	pkgs, err := packages.Load(&packages.Config{Mode: packages.LoadAllSyntax, Tests: true}, flag.Args()...)
+	if err != nil {
+		log.Fatalf("Error loading packages: %v", err)
+	}
+	if packages.PrintErrors(pkgs) > 0 {
+		os.Exit(1)
+	}
+
+	graph, err := checker.Analyze([]*analysis.Analyzer{flagusage.Analyzer}, pkgs, nil)
+	if err != nil {
+		log.Fatalf("Error analyzing packages: %v", err)
+	}
+
+	// Test variants of the packages repeat the diagnostics of their files
+	reported := map[string]bool{}
+	var refs []flagusage.Reference
+	for _, act := range graph.Roots {
+		if act.Err != nil {
+			log.Fatalf("Error analyzing %s: %v", act.Package.PkgPath, act.Err)
+		}
+
+		for _, d := range act.Diagnostics {
+			reported[fmt.Sprintf("%s: %s", act.Package.Fset.Position(d.Pos), d.Message)] = true
+		}
+		refs = append(refs, act.Result.([]flagusage.Reference)...)
+	}
+
+	lines := make([]string, 0, len(reported))
+	for line := range reported {
+		lines = append(lines, line)
+	}
+	sort.Strings(lines)
+	for _, line := range lines {
+		fmt.Fprintln(os.Stderr, line)
+	}
+
+	if *unreferenced {
+		service := flag.Lookup("service").Value.String()
+		flags, err := flagusage.FetchFlags(server, flag.Lookup("api-path").Value.String(), service)
+		if err != nil {
+			log.Fatalf("Error fetching server flags: %v", err)
+		}
+
+		for _, f := range flagusage.Unreferenced(flags, refs, service) {
+			lines = append(lines, f.String())
+			fmt.Fprintf(os.Stderr, "flag %s isn't referenced\n", f)
+		}
+	}
+
+	if len(lines) > 0 {
+		os.Exit(3)
+	}
+}
@@ -0,0 +1,234 @@
+// Package flagusage provides an analyzer reporting the flag names referenced
+// by toggle calls with constant names, which aren't normalized or don't exist
+// on the toggle server.
+package flagusage
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"net/http"
+	"path"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/globusdigital/feature-toggles/toggle"
+	"golang.org/x/tools/go/analysis"
+)
+
+const togglePkg = "github.com/globusdigital/feature-toggles/toggle"
+
+// Reference is a flag name referenced by a toggle call
+type Reference struct {
+	// Name is the normalized flag name
+	Name string
+	// Literal is the name as written in the code
+	Literal string
+	// Pos and End delimit the name argument, or the call if the name isn't a
+	// string literal
+	Pos, End token.Pos
+}
+
+// Analyzer reports the flag names of toggle calls which aren't normalized, and
+// the ones missing on the toggle server when the server flag is set. Its
+// result is the []Reference of the package.
+var Analyzer = &analysis.Analyzer{
+	Name:       "flagusage",
+	Doc:        "check the flag names referenced by toggle calls",
+	Run:        run,
+	ResultType: reflect.TypeOf([]Reference(nil)),
+}
+
+var (
+	server, service, apiPath string
+
+	serverFlags     map[string]bool
+	serverFlagsErr  error
+	serverFlagsOnce sync.Once
+)
+
+func init() {
+	Analyzer.Flags.StringVar(&server, "server", "", "the toggle server address, such as http://toggles:8080. Missing flags are reported when set")
+	Analyzer.Flags.StringVar(&service, "service", "", "the service name, limiting the server flags to its own and the global ones")
+	Analyzer.Flags.StringVar(&apiPath, "api-path", "/flags", "the toggle server api path")
+}
+
+// nameArgs holds the index of the flag name argument of the toggle functions
+// and Client methods
+var nameArgs = map[string]int{
+	"Get": 0, "GetRaw": 0, "GetInt": 0, "GetFloat": 0, "GetDuration": 0, "GetJSON": 0,
+	"Variant": 0, "GetVariant": 0, "Evaluate": 0, "OnChange": 0,
+	"GetCtx": 1, "GetRawCtx": 1, "GetIntCtx": 1, "GetFloatCtx": 1, "GetDurationCtx": 1, "GetJSONCtx": 1,
+	"VariantCtx": 1, "EvaluateCtx": 1,
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	var names map[string]bool
+	if server != "" {
+		serverFlagsOnce.Do(func() {
+			serverFlags, serverFlagsErr = fetchFlags(server, apiPath, service)
+		})
+		if serverFlagsErr != nil {
+			return nil, serverFlagsErr
+		}
+		names = serverFlags
+	}
+
+	refs := References(pass.TypesInfo, pass.Files)
+	for _, ref := range refs {
+		if ref.Literal != ref.Name {
+			pass.Report(analysis.Diagnostic{
+				Pos:     ref.Pos,
+				Message: fmt.Sprintf("flag name %q is normalized to %q", ref.Literal, ref.Name),
+				SuggestedFixes: []analysis.SuggestedFix{{
+					Message: "Use the normalized name",
+					TextEdits: []analysis.TextEdit{{
+						Pos:     ref.Pos,
+						End:     ref.End,
+						NewText: []byte(fmt.Sprintf("%q", ref.Name)),
+					}},
+				}},
+			})
+		}
+
+		if names != nil && !names[ref.Name] {
+			pass.Reportf(ref.Pos, "flag %q doesn't exist on the server", ref.Name)
+		}
+	}
+
+	return refs, nil
+}
+
+// References returns the flag names referenced by the toggle calls of the
+// files with a constant name
+func References(info *types.Info, files []*ast.File) []Reference {
+	var refs []Reference
+	for _, f := range files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+
+			idx, ok := toggleCall(info, call)
+			if !ok || idx >= len(call.Args) {
+				return true
+			}
+
+			arg := call.Args[idx]
+			tv, ok := info.Types[arg]
+			if !ok || tv.Value == nil || tv.Value.Kind() != constant.String {
+				return true
+			}
+
+			literal := constant.StringVal(tv.Value)
+			ref := Reference{Name: toggle.NormalizeName(literal), Literal: literal, Pos: call.Pos(), End: call.End()}
+			if lit, ok := ast.Unparen(arg).(*ast.BasicLit); ok {
+				ref.Pos, ref.End = lit.Pos(), lit.End()
+			} else {
+				// Only literals can be fixed in place
+				ref.Literal = ref.Name
+			}
+			refs = append(refs, ref)
+
+			return true
+		})
+	}
+
+	return refs
+}
+
+// toggleCall returns the name argument index if the call is to a toggle
+// package function or Client method taking a flag name
+func toggleCall(info *types.Info, call *ast.CallExpr) (int, bool) {
+	sel, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr)
+	if !ok {
+		return 0, false
+	}
+
+	fn, ok := info.Uses[sel.Sel].(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != togglePkg {
+		return 0, false
+	}
+
+	sig := fn.Type().(*types.Signature)
+	if recv := sig.Recv(); recv != nil {
+		t := recv.Type()
+		if p, ok := t.(*types.Pointer); ok {
+			t = p.Elem()
+		}
+		if named, ok := t.(*types.Named); !ok || named.Obj().Name() != "Client" {
+			return 0, false
+		}
+	}
+
+	idx, ok := nameArgs[fn.Name()]
+	return idx, ok
+}
+
+// fetchFlags returns the normalized names of the flags on the server, limited
+// to the service and global ones if the service is set
+func fetchFlags(server, apiPath, service string) (map[string]bool, error) {
+	flags, err := FetchFlags(server, apiPath, service)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]bool, len(flags))
+	for _, f := range flags {
+		names[f.Name] = true
+	}
+
+	return names, nil
+}
+
+// FetchFlags returns the flags on the server, limited to the service and
+// global ones if the service is set
+func FetchFlags(server, apiPath, service string) ([]toggle.Flag, error) {
+	client := http.Client{Timeout: 10 * time.Second}
+
+	resp, err := client.Get(strings.TrimSuffix(server, "/") + path.Join(apiPath, service))
+	if err != nil {
+		return nil, fmt.Errorf("getting server flags: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("invalid status code for server flags: %d (%s)", resp.StatusCode, resp.Status)
+	}
+
+	var flags []toggle.Flag
+	if err := json.NewDecoder(resp.Body).Decode(&flags); err != nil {
+		return nil, fmt.Errorf("decoding server flags: %v", err)
+	}
+
+	for i := range flags {
+		flags[i] = flags[i].Normalized()
+	}
+
+	return flags, nil
+}
+
+// Unreferenced returns the flags which aren't referenced. Global flags are
+// only considered when the service is empty, as other services may use them.
+func Unreferenced(flags []toggle.Flag, refs []Reference, service string) []toggle.Flag {
+	referenced := map[string]bool{}
+	for _, ref := range refs {
+		referenced[ref.Name] = true
+	}
+
+	var ret []toggle.Flag
+	for _, f := range flags {
+		if referenced[f.Name] || service != "" && f.ServiceName == "" {
+			continue
+		}
+		ret = append(ret, f)
+	}
+
+	return ret
+}
@@ -0,0 +1,86 @@
+package flagusage
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/globusdigital/feature-toggles/toggle"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+var serverData = []toggle.Flag{
+	{Name: "checkout", ServiceName: "serv1", RawValue: "t", Value: true},
+	{Name: "workers", ServiceName: "serv1", RawValue: "4"},
+	{Name: "theme", RawValue: "dark"},
+	{Name: "stale", ServiceName: "serv1", RawValue: "t", Value: true},
+	{Name: "global.stale", RawValue: "t", Value: true},
+}
+
+func TestAnalyzer(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/flags/serv1" {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(serverData)
+	}))
+	defer ts.Close()
+
+	setFlags(t, map[string]string{"server": ts.URL, "service": "serv1"})
+
+	results := analysistest.RunWithSuggestedFixes(t, analysistest.TestData(), Analyzer, "a")
+
+	refs := results[0].Result.([]Reference)
+	assert.Equal(t, []string{"checkout", "new.checkout", "checkout", "workers", "payments", "workers", "theme", "theme"}, names(refs))
+	assert.Equal(t, []toggle.Flag{serverData[3]}, Unreferenced(serverData, refs, "serv1"))
+	assert.Equal(t, []toggle.Flag{serverData[3], serverData[4]}, Unreferenced(serverData, refs, ""))
+}
+
+func TestAnalyzer_serverError(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	defer ts.Close()
+
+	setFlags(t, map[string]string{"server": ts.URL})
+
+	results := analysistest.Run(&fakeT{T: t}, analysistest.TestData(), Analyzer, "a")
+	assert.Error(t, results[0].Err)
+}
+
+// setFlags sets the analyzer flags, and resets them along with the fetched
+// server flags when the test finishes
+func setFlags(t *testing.T, values map[string]string) {
+	reset := func() {
+		serverFlags, serverFlagsErr, serverFlagsOnce = nil, nil, sync.Once{}
+	}
+	reset()
+
+	for name, v := range values {
+		f := Analyzer.Flags.Lookup(name)
+		def := f.DefValue
+		assert.NoError(t, f.Value.Set(v))
+		t.Cleanup(func() {
+			_ = f.Value.Set(def)
+		})
+	}
+	t.Cleanup(reset)
+}
+
+func names(refs []Reference) []string {
+	ret := make([]string, len(refs))
+	for i, ref := range refs {
+		ret[i] = ref.Name
+	}
+
+	return ret
+}
+
+// fakeT ignores the errors reported by analysistest for the failed analysis
+type fakeT struct {
+	*testing.T
+}
+
+func (t *fakeT) Errorf(format string, args ...interface{}) {}
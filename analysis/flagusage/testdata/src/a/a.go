@@ -0,0 +1,22 @@
+package a
+
+import (
+	"context"
+
+	"github.com/globusdigital/feature-toggles/toggle"
+)
+
+const checkout = "checkout"
+
+func flags(ctx context.Context, c *toggle.Client, name string) {
+	toggle.Get("checkout")
+	toggle.Get("New-Checkout") // want `flag name "New-Checkout" is normalized to "new.checkout"` `flag "new.checkout" doesn't exist on the server`
+	toggle.GetRaw(checkout, toggle.ForString("tenant", "t1"))
+	toggle.GetInt("workers", 4)
+	toggle.GetCtx(ctx, "payments") // want `flag "payments" doesn't exist on the server`
+	toggle.Get(name)
+
+	c.Get("Workers") // want `flag name "Workers" is normalized to "workers"`
+	c.GetRawCtx(ctx, "theme")
+	c.OnChange("Theme", func() {}) // want `flag name "Theme" is normalized to "theme"`
+}
@@ -0,0 +1,17 @@
+// Package toggle is a stub of the toggle package API used by the tests
+package toggle
+
+import "context"
+
+type Option func()
+
+type Client struct{}
+
+func Get(name string, opts ...Option) bool                                          { return false }
+func GetRaw(name string, opts ...Option) string                                     { return "" }
+func GetInt(name string, def int64, opts ...Option) int64                           { return def }
+func GetCtx(ctx context.Context, name string, opts ...Option) bool                  { return false }
+func ForString(name string, value string) Option                                    { return nil }
+func (c *Client) Get(name string, opts ...Option) bool                              { return false }
+func (c *Client) GetRawCtx(ctx context.Context, name string, opts ...Option) string { return "" }
+func (c *Client) OnChange(name string, fn func()) func()                            { return nil }
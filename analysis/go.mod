@@ -0,0 +1,19 @@
+module github.com/globusdigital/feature-toggles/analysis
+
+go 1.25.0
+
+require (
+	github.com/globusdigital/feature-toggles v0.0.0-00010101000000-000000000000
+	github.com/stretchr/testify v1.8.4
+	golang.org/x/tools v0.38.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/mod v0.29.0 // indirect
+	golang.org/x/sync v0.17.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/globusdigital/feature-toggles => ..
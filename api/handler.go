@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/globusdigital/feature-toggles/toggle"
@@ -20,33 +21,77 @@ type EventBus interface {
 
 type Store interface {
 	Get(ctx context.Context, serviceName string) ([]toggle.Flag, error)
+	Revision(ctx context.Context, serviceName string) (int64, error)
 	Save(ctx context.Context, flags []toggle.Flag, initial bool) error
 	Delete(ctx context.Context, flags []toggle.Flag) error
+
+	Definitions(ctx context.Context, serviceName string) ([]toggle.Definition, error)
+	SaveDefinitions(ctx context.Context, serviceName string, defs []toggle.Definition) error
 }
 
+// Handler creates the flags API handler. Saved and deleted flags are sent to
+// the bus, and streamed to the clients of the service stream endpoint. If the
+// bus is also a toggle.EventBus, the streamed events are received from it for
+// the lifetime of the process, so that the streams of every server replica
+// get the flags saved through any of them.
 func Handler(path string, store Store, bus EventBus) http.Handler {
 	r := chi.NewMux()
+	b := newBroker()
+
+	// The local broker publishes the saved flags, unless they are relayed
+	// from the bus
+	local := b
+	if receiver, ok := bus.(toggle.EventBus); ok {
+		if ch := receiver.Receiver(context.Background()); ch != nil {
+			go b.relay(ch)
+			local = nil
+		}
+	}
 
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 
 	r.Route(path, func(r chi.Router) {
 		r.With(middleware.Timeout(time.Second*2)).Get("/", getAllFlags(store))
+		r.With(middleware.Timeout(time.Second*2)).Get("/definitions", getAllDefinitions(store))
 
 		r.Route("/{serviceName}", func(r chi.Router) {
+			r.Use(serviceCtx)
 			r.With(middleware.Timeout(time.Second*2)).Get("/", getFlags(store))
-			r.With(middleware.Timeout(time.Second*10), flagsCtx).Post("/", saveFlags(store, bus))
-			r.With(middleware.Timeout(time.Second*10), flagsCtx).Delete("/", deleteFlags(store, bus))
+			r.With(middleware.Timeout(time.Second*10), flagsCtx).Post("/", saveFlags(store, bus, local))
+			r.With(middleware.Timeout(time.Second*10), flagsCtx).Delete("/", deleteFlags(store, bus, local))
+			r.Get("/stream", streamFlags(b))
 
 			r.Route("/initial", func(r chi.Router) {
 				r.With(middleware.Timeout(time.Second*12), flagsCtx).Post("/", saveInitialFlags(store))
 			})
+
+			r.Route("/definitions", func(r chi.Router) {
+				r.With(middleware.Timeout(time.Second*2)).Get("/", getDefinitions(store))
+				r.With(middleware.Timeout(time.Second*10)).Post("/", saveDefinitions(store))
+			})
 		})
 	})
 
 	return r
 }
 
+// reservedServiceName is the path of the definitions of all services, which
+// would shadow the flags of a service with the same name
+const reservedServiceName = "definitions"
+
+// serviceCtx rejects the requests for the reserved service name
+func serviceCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.EqualFold(chi.URLParam(r, "serviceName"), reservedServiceName) {
+			http.Error(w, fmt.Sprintf("Reserved service name %q", reservedServiceName), http.StatusBadRequest)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 type flagsCtxType string
 
 var flagsKey flagsCtxType = "flags"
@@ -86,8 +131,8 @@ func flagsCtx(next http.Handler) http.Handler {
 				return
 			}
 
-			if err := f.Condition.Validate(); err != nil {
-				http.Error(w, fmt.Sprintf("Invalid flag %s condition: %v", f, err), http.StatusBadRequest)
+			if err := f.Validate(); err != nil {
+				http.Error(w, fmt.Sprintf("Invalid flag %s: %v", f, err), http.StatusBadRequest)
 				return
 			}
 		}
@@ -99,25 +144,31 @@ func flagsCtx(next http.Handler) http.Handler {
 
 func getAllFlags(store Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		getFlagsForServiceName(r.Context(), "", store, w)
+		getFlagsForServiceName(r.Context(), "", store, w, r.Header.Get("If-None-Match"))
 	}
 }
 
 func getFlags(store Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		serviceName := chi.URLParam(r, "serviceName")
-		getFlagsForServiceName(r.Context(), serviceName, store, w)
+		getFlagsForServiceName(r.Context(), serviceName, store, w, r.Header.Get("If-None-Match"))
 	}
 }
 
-func saveFlags(store Store, bus EventBus) http.HandlerFunc {
+func saveFlags(store Store, bus EventBus, b *broker) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 		flags := getFlagsFromCtx(ctx)
 		if saveFlagsForService(ctx, flags, false, store, w) {
 			return
 		}
-		if err := bus.Send(ctx, toggle.Event{Type: toggle.SaveEvent, Flags: flags}); err != nil {
+		ev := toggle.Event{Type: toggle.SaveEvent, Flags: flags}
+		if b != nil {
+			// The broker gets its own flags, as event bus receivers may modify them
+			b.publish(toggle.Event{Type: ev.Type, Flags: append([]toggle.Flag(nil), flags...)})
+		}
+
+		if err := bus.Send(ctx, ev); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
@@ -133,18 +184,24 @@ func saveInitialFlags(store Store) http.HandlerFunc {
 			return
 		}
 		serviceName := chi.URLParam(r, "serviceName")
-		getFlagsForServiceName(r.Context(), serviceName, store, w)
+		getFlagsForServiceName(r.Context(), serviceName, store, w, "")
 	}
 }
 
-func deleteFlags(store Store, bus EventBus) http.HandlerFunc {
+func deleteFlags(store Store, bus EventBus, b *broker) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 		flags := getFlagsFromCtx(ctx)
 		if deleteFlagsForService(ctx, flags, store, w) {
 			return
 		}
-		if err := bus.Send(ctx, toggle.Event{Type: toggle.DeleteEvent, Flags: flags}); err != nil {
+		ev := toggle.Event{Type: toggle.DeleteEvent, Flags: flags}
+		if b != nil {
+			// The broker gets its own flags, as event bus receivers may modify them
+			b.publish(toggle.Event{Type: ev.Type, Flags: append([]toggle.Flag(nil), flags...)})
+		}
+
+		if err := bus.Send(ctx, ev); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
@@ -152,7 +209,25 @@ func deleteFlags(store Store, bus EventBus) http.HandlerFunc {
 	}
 }
 
-func getFlagsForServiceName(ctx context.Context, serviceName string, store Store, w http.ResponseWriter) {
+// getFlagsForServiceName writes the flags along with their revision as an
+// ETag, or only the status if the revision matches the ifNoneMatch header
+func getFlagsForServiceName(ctx context.Context, serviceName string, store Store, w http.ResponseWriter, ifNoneMatch string) {
+	// The revision is obtained first, so that a concurrent change can only
+	// cause an outdated ETag, and not outdated flags
+	rev, err := store.Revision(ctx, serviceName)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	etag := fmt.Sprintf(`"%d"`, rev)
+	w.Header().Set("ETag", etag)
+
+	if etagMatch(ifNoneMatch, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
 	flags, err := store.Get(ctx, serviceName)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -168,6 +243,18 @@ func getFlagsForServiceName(ctx context.Context, serviceName string, store Store
 	_, _ = w.Write(b)
 }
 
+// etagMatch reports whether the If-None-Match header value contains the etag
+func etagMatch(header, etag string) bool {
+	for _, v := range strings.Split(header, ",") {
+		v = strings.TrimPrefix(strings.TrimSpace(v), "W/")
+		if v == etag || v == "*" {
+			return true
+		}
+	}
+
+	return false
+}
+
 func saveFlagsForService(ctx context.Context, flags []toggle.Flag, initial bool, store Store, w http.ResponseWriter) bool {
 	if err := store.Save(ctx, flags, initial); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -187,6 +274,70 @@ func deleteFlagsForService(ctx context.Context, flags []toggle.Flag, store Store
 	return false
 }
 
+func getAllDefinitions(store Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeDefinitions(r.Context(), "", store, w)
+	}
+}
+
+func getDefinitions(store Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeDefinitions(r.Context(), chi.URLParam(r, "serviceName"), store, w)
+	}
+}
+
+func writeDefinitions(ctx context.Context, serviceName string, store Store, w http.ResponseWriter) {
+	defs, err := store.Definitions(ctx, serviceName)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	b, err := json.Marshal(defs)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	_, _ = w.Write(b)
+}
+
+// saveDefinitions replaces the flag definitions of the service with the given
+// ones
+func saveDefinitions(store Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var defs []toggle.Definition
+		if err := json.NewDecoder(r.Body).Decode(&defs); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		serviceName := chi.URLParam(r, "serviceName")
+
+		for i, d := range defs {
+			d = d.Normalized()
+			defs[i] = d
+
+			if d.ServiceName != serviceName {
+				http.Error(w, fmt.Sprintf("Invalid definition service %q for flag %s", d.ServiceName, d.Name), http.StatusBadRequest)
+				return
+			}
+
+			if err := d.Validate(); err != nil {
+				http.Error(w, fmt.Sprintf("Invalid definition for flag %s: %v", d.Name, err), http.StatusBadRequest)
+				return
+			}
+		}
+
+		if err := store.SaveDefinitions(r.Context(), serviceName, defs); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
 func getFlagsFromCtx(ctx context.Context) []toggle.Flag {
 	if flags, ok := ctx.Value(flagsKey).([]toggle.Flag); ok {
 		return flags
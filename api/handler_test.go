@@ -3,6 +3,7 @@ package api
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http/httptest"
 	"strings"
 	"testing"
@@ -20,8 +21,22 @@ var (
 		{Name: "flag4", ServiceName: "svc2", RawValue: "some string"},
 	}
 
-	strFlags1 = `[{"name": "flag10", "service": "svc1", "raw": "1", "value": true}, {"name": "flag11", "service": "svc2", "raw": "1", "value": true}]`
-	strFlags2 = `[{"name": "flag10", "service": "svc1", "raw": "1", "value": true}, {"name": "flag11", "service": "", "raw": "raw string"}]`
+	strFlags1    = `[{"name": "flag10", "service": "svc1", "raw": "1", "value": true}, {"name": "flag11", "service": "svc2", "raw": "1", "value": true}]`
+	strFlags2    = `[{"name": "flag10", "service": "svc1", "raw": "1", "value": true}, {"name": "flag11", "service": "", "raw": "raw string"}]`
+	strFlags3    = `[{"name": "flag10", "service": "svc1", "raw": "1", "value": true, "variants": [{"name": "a", "type": 0, "value": 1, "weight": 50}, {"name": "b", "type": 0, "value": 2, "weight": 50}]}]`
+	strFlags4    = `[{"name": "flag10", "service": "svc1", "raw": "1", "value": true, "variants": [{"name": "a", "type": 0, "value": "1", "weight": 50}]}]`
+	definitions1 = []toggle.Definition{
+		{Name: "flag1", ServiceName: "svc1", Type: toggle.BoolFlag, Default: "f", Description: "Enables flag1"},
+		{Name: "flag4", ServiceName: "svc2", Type: toggle.IntFlag},
+	}
+
+	strDefinitions1 = `[{"name": "Flag1", "service": "svc1", "type": "bool", "default": "f", "description": "Enables flag1"}]`
+	strDefinitions2 = `[{"name": "flag1", "service": "svc2", "type": "bool"}]`
+	strDefinitions3 = `[{"name": "flag1", "service": "svc1", "type": "int", "default": "f"}]`
+
+	strFlags5 = `[{"name": "flag10", "service": "svc1", "raw": "1", "value": true, "rules": [{"cond": {"fields": [{"name": "userID", "type": 0, "value": "1"}]}, "raw": "2"}]}]`
+	strFlags6 = `[{"name": "flag10", "service": "svc1", "raw": "1", "value": true, "activeFrom": "2021-03-01T09:00:00Z", "activeUntil": "2021-03-03T00:00:00Z", "expr": "now < 2021-03-02"}]`
+	strFlags7 = `[{"name": "flag10", "service": "svc1", "raw": "1", "value": true, "activeFrom": "2021-03-03T00:00:00Z", "activeUntil": "2021-03-01T09:00:00Z"}]`
 )
 
 func TestHandler(t *testing.T) {
@@ -36,9 +51,16 @@ func TestHandler(t *testing.T) {
 		flags       []toggle.Flag
 		flagsErr    error
 
+		revision    int64
+		revisionErr error
+		ifNoneMatch string
+
 		saveInitial  bool
 		flagsSaveErr error
 
+		definitions    []toggle.Definition
+		definitionsErr error
+
 		sendErr error
 
 		wantCode int
@@ -50,12 +72,25 @@ func TestHandler(t *testing.T) {
 
 		{name: "get flags svc1 err", url: "/flags/svc1", serviceName: "svc1", flagsErr: errors.New("flags get"), wantCode: 500},
 		{name: "get flags svc1", url: "/flags/svc1", serviceName: "svc1", flags: flags1[:3], wantCode: 200, want: flags1[:3]},
+		{name: "get flags svc1 revision err", url: "/flags/svc1", serviceName: "svc1", flags: flags1[:3], revisionErr: errors.New("revision get"), wantCode: 500},
+		{name: "get flags svc1 modified", url: "/flags/svc1", serviceName: "svc1", flags: flags1[:3], revision: 4, ifNoneMatch: `"3"`, wantCode: 200, want: flags1[:3]},
+		{name: "get flags svc1 not modified", url: "/flags/svc1", serviceName: "svc1", flags: flags1[:3], revision: 4, ifNoneMatch: `"4"`, wantCode: 304},
+		{name: "get flags svc1 not modified weak", url: "/flags/svc1", serviceName: "svc1", flags: flags1[:3], revision: 4, ifNoneMatch: `"2", W/"4"`, wantCode: 304},
+		{name: "get all flags not modified", url: "/flags", flags: flags1, revision: 7, ifNoneMatch: `"7"`, wantCode: 304},
 
 		{name: "save flags svc1, no body", method: "POST", url: "/flags/svc1", serviceName: "svc1", wantCode: 400},
 		{name: "save flags svc1 invalid", method: "POST", url: "/flags/svc1", body: strFlags1, serviceName: "svc1", wantCode: 400},
 		{name: "save flags svc1 save err", method: "POST", url: "/flags/svc1", body: strFlags2, serviceName: "svc1", flagsSaveErr: errors.New("save err"), wantCode: 500},
 		{name: "save flags svc1 send err", method: "POST", url: "/flags/svc1", body: strFlags2, serviceName: "svc1", sendErr: errors.New("save err"), wantCode: 500},
 		{name: "save flags svc1", method: "POST", url: "/flags/svc1", body: strFlags2, serviceName: "svc1", wantCode: 204},
+		{name: "save flags svc1 variants", method: "POST", url: "/flags/svc1", body: strFlags3, serviceName: "svc1", wantCode: 204},
+		{name: "save flags svc1 invalid rules", method: "POST", url: "/flags/svc1", body: strFlags5, serviceName: "svc1", wantCode: 400},
+		{name: "save flags svc1 invalid variants", method: "POST", url: "/flags/svc1", body: strFlags4, serviceName: "svc1", wantCode: 400},
+		{name: "save flags svc1 activation window", method: "POST", url: "/flags/svc1", body: strFlags6, serviceName: "svc1", wantCode: 204},
+		{name: "save flags svc1 invalid activation window", method: "POST", url: "/flags/svc1", body: strFlags7, serviceName: "svc1", wantCode: 400},
+
+		{name: "save flags reserved service", method: "POST", url: "/flags/definitions", body: `[{"name": "flag10", "service": "definitions", "raw": "1", "value": true}]`, serviceName: "definitions", wantCode: 400},
+		{name: "save definitions reserved service", method: "POST", url: "/flags/Definitions/definitions", body: strDefinitions1, serviceName: "definitions", wantCode: 400},
 
 		{name: "delete flags svc1, no body", method: "DELETE", url: "/flags/svc1", serviceName: "svc1", wantCode: 400},
 		{name: "delete flags svc1 invalid", method: "DELETE", url: "/flags/svc1", body: strFlags1, serviceName: "svc1", wantCode: 400},
@@ -67,6 +102,16 @@ func TestHandler(t *testing.T) {
 		{name: "save initial flags svc1 invalid", method: "POST", url: "/flags/svc1/initial", body: strFlags1, serviceName: "svc1", saveInitial: true, wantCode: 400},
 		{name: "save initial flags svc1 save err", method: "POST", url: "/flags/svc1/initial", body: strFlags2, serviceName: "svc1", flagsSaveErr: errors.New("save err"), saveInitial: true, wantCode: 500},
 		{name: "save initial flags svc1", method: "POST", url: "/flags/svc1/initial", body: strFlags2, serviceName: "svc1", saveInitial: true, flags: flags1, wantCode: 200, want: flags1},
+		{name: "get all definitions", url: "/flags/definitions", definitions: definitions1, wantCode: 200, want: definitions1},
+		{name: "get all definitions err", url: "/flags/definitions", definitionsErr: errors.New("definitions get"), wantCode: 500},
+		{name: "get definitions svc1", url: "/flags/svc1/definitions", serviceName: "svc1", definitions: definitions1[:1], wantCode: 200, want: definitions1[:1]},
+		{name: "save definitions svc1, no body", method: "POST", url: "/flags/svc1/definitions", serviceName: "svc1", wantCode: 400},
+		{name: "save definitions svc1 other service", method: "POST", url: "/flags/svc1/definitions", body: strDefinitions2, serviceName: "svc1", wantCode: 400},
+		{name: "save definitions svc1 invalid default", method: "POST", url: "/flags/svc1/definitions", body: strDefinitions3, serviceName: "svc1", wantCode: 400},
+		{name: "save definitions svc1 save err", method: "POST", url: "/flags/svc1/definitions", body: strDefinitions1, serviceName: "svc1", definitionsErr: errors.New("save err"), wantCode: 500},
+		{name: "save definitions svc1", method: "POST", url: "/flags/svc1/definitions", body: strDefinitions1, serviceName: "svc1", wantCode: 204},
+
+		{name: "save initial flags svc1 ignores If-None-Match", method: "POST", url: "/flags/svc1/initial", body: strFlags2, serviceName: "svc1", saveInitial: true, flags: flags1, revision: 2, ifNoneMatch: `"2"`, wantCode: 200, want: flags1},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -75,22 +120,37 @@ func TestHandler(t *testing.T) {
 
 			store, bus := NewMockStore(ctrl), NewMockBus(ctrl)
 			store.EXPECT().Get(gomock.Any(), gomock.Eq(tt.serviceName)).AnyTimes().Return(tt.flags, tt.flagsErr)
+			store.EXPECT().Revision(gomock.Any(), gomock.Eq(tt.serviceName)).AnyTimes().Return(tt.revision, tt.revisionErr)
 			store.EXPECT().Save(gomock.Any(), gomock.Any(), gomock.Eq(tt.saveInitial)).AnyTimes().Return(tt.flagsSaveErr)
 			store.EXPECT().Delete(gomock.Any(), gomock.Any()).AnyTimes().Return(tt.flagsSaveErr)
+			store.EXPECT().Definitions(gomock.Any(), gomock.Eq(tt.serviceName)).AnyTimes().Return(tt.definitions, tt.definitionsErr)
+			store.EXPECT().SaveDefinitions(gomock.Any(), gomock.Eq(tt.serviceName), gomock.Eq(definitions1[:1])).AnyTimes().Return(tt.definitionsErr)
 
 			bus.EXPECT().Send(gomock.Any(), gomock.Any()).AnyTimes().Return(tt.sendErr)
 
 			w, r := httptest.NewRecorder(), httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			if tt.ifNoneMatch != "" {
+				r.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
 
 			Handler("/flags", store, bus).ServeHTTP(w, r)
 
 			a := assert.New(t)
 			a.Equal(tt.wantCode, w.Code, w.Body.String())
 
+			if w.Code == 304 {
+				a.Equal(fmt.Sprintf(`"%d"`, tt.revision), w.Header().Get("ETag"))
+				a.Empty(w.Body.String())
+			}
+
 			if w.Code != 200 {
 				return
 			}
 
+			if _, ok := tt.want.([]toggle.Definition); !ok {
+				a.Equal(fmt.Sprintf(`"%d"`, tt.revision), w.Header().Get("ETag"))
+			}
+
 			b, err := json.Marshal(tt.want)
 			a.NoError(err)
 			a.Equal(string(b), w.Body.String())
@@ -34,6 +34,21 @@ func (m *MockStore) EXPECT() *MockStoreMockRecorder {
 	return m.recorder
 }
 
+// Definitions mocks base method
+func (m *MockStore) Definitions(arg0 context.Context, arg1 string) ([]toggle.Definition, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Definitions", arg0, arg1)
+	ret0, _ := ret[0].([]toggle.Definition)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Definitions indicates an expected call of Definitions
+func (mr *MockStoreMockRecorder) Definitions(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Definitions", reflect.TypeOf((*MockStore)(nil).Definitions), arg0, arg1)
+}
+
 // Delete mocks base method
 func (m *MockStore) Delete(arg0 context.Context, arg1 []toggle.Flag) error {
 	m.ctrl.T.Helper()
@@ -63,6 +78,21 @@ func (mr *MockStoreMockRecorder) Get(arg0, arg1 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockStore)(nil).Get), arg0, arg1)
 }
 
+// Revision mocks base method
+func (m *MockStore) Revision(arg0 context.Context, arg1 string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Revision", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Revision indicates an expected call of Revision
+func (mr *MockStoreMockRecorder) Revision(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Revision", reflect.TypeOf((*MockStore)(nil).Revision), arg0, arg1)
+}
+
 // Save mocks base method
 func (m *MockStore) Save(arg0 context.Context, arg1 []toggle.Flag, arg2 bool) error {
 	m.ctrl.T.Helper()
@@ -76,3 +106,17 @@ func (mr *MockStoreMockRecorder) Save(arg0, arg1, arg2 interface{}) *gomock.Call
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockStore)(nil).Save), arg0, arg1, arg2)
 }
+
+// SaveDefinitions mocks base method
+func (m *MockStore) SaveDefinitions(arg0 context.Context, arg1 string, arg2 []toggle.Definition) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveDefinitions", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveDefinitions indicates an expected call of SaveDefinitions
+func (mr *MockStoreMockRecorder) SaveDefinitions(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveDefinitions", reflect.TypeOf((*MockStore)(nil).SaveDefinitions), arg0, arg1, arg2)
+}
@@ -0,0 +1,240 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/globusdigital/feature-toggles/toggle"
+	"github.com/go-chi/chi"
+)
+
+const (
+	// streamBufferSize is the number of recent events kept for resuming
+	// interrupted streams
+	streamBufferSize = 256
+	// streamSubscriberBuffer is the number of events a stream may lag behind
+	// before it is closed
+	streamSubscriberBuffer = 16
+)
+
+var streamPingInterval = 15 * time.Second
+
+type streamEvent struct {
+	id    uint64
+	event toggle.Event
+}
+
+// broker relays the flag events to the stream subscribers. Recent events are
+// kept so that streams can be resumed using the Last-Event-ID header.
+type broker struct {
+	// epoch distinguishes the event ids of different server runs. Event ids
+	// are only valid for the server process which produced them, so resuming
+	// a stream on another server replica starts with a resync event.
+	epoch int64
+
+	mu     sync.Mutex
+	lastID uint64
+	events []streamEvent
+	subs   map[chan streamEvent]struct{}
+}
+
+type subscription struct {
+	ch      chan streamEvent
+	backlog []streamEvent
+
+	// resync is set when the events after the requested id are not available
+	resync bool
+	lastID uint64
+}
+
+func newBroker() *broker {
+	return &broker{epoch: time.Now().UnixNano(), subs: map[chan streamEvent]struct{}{}}
+}
+
+func (b *broker) publish(ev toggle.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	se := streamEvent{id: b.lastID, event: ev}
+
+	if len(b.events) == streamBufferSize {
+		b.events = b.events[1:]
+	}
+	b.events = append(b.events, se)
+
+	for ch := range b.subs {
+		select {
+		case ch <- se:
+		default:
+			// The subscriber is too slow, it will have to resume
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// relay publishes the save and delete events received from the event bus
+func (b *broker) relay(ch <-chan toggle.Event) {
+	for ev := range ch {
+		if ev.Type == toggle.SaveEvent || ev.Type == toggle.DeleteEvent {
+			b.publish(ev)
+		}
+	}
+}
+
+// subscribe registers a new subscriber which receives the events after the
+// given event id. All new events are received for an empty id.
+func (b *broker) subscribe(lastEventID string) subscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s := subscription{ch: make(chan streamEvent, streamSubscriberBuffer), lastID: b.lastID}
+	b.subs[s.ch] = struct{}{}
+
+	if lastEventID == "" {
+		return s
+	}
+
+	id, ok := b.parseID(lastEventID)
+	oldest := b.lastID + 1
+	if len(b.events) > 0 {
+		oldest = b.events[0].id
+	}
+
+	if !ok || id > b.lastID || id+1 < oldest {
+		s.resync = true
+		return s
+	}
+
+	for _, se := range b.events {
+		if se.id > id {
+			s.backlog = append(s.backlog, se)
+		}
+	}
+
+	return s
+}
+
+func (b *broker) unsubscribe(ch chan streamEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subs[ch]; ok {
+		delete(b.subs, ch)
+		close(ch)
+	}
+}
+
+func (b *broker) formatID(id uint64) string {
+	return fmt.Sprintf("%d-%d", b.epoch, id)
+}
+
+// parseID parses an event id, which is only valid if it was produced by the
+// same broker
+func (b *broker) parseID(v string) (uint64, bool) {
+	parts := strings.SplitN(v, "-", 2)
+	if len(parts) != 2 || parts[0] != strconv.FormatInt(b.epoch, 10) {
+		return 0, false
+	}
+
+	id, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return id, true
+}
+
+// streamFlags streams the save and delete events of the service flags as
+// server-sent events. A resync event is sent first when the stream can't be
+// resumed from the Last-Event-ID header.
+func streamFlags(b *broker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+
+		serviceName := chi.URLParam(r, "serviceName")
+
+		s := b.subscribe(r.Header.Get("Last-Event-ID"))
+		defer b.unsubscribe(s.ch)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+
+		if s.resync {
+			if err := writeStreamEvent(w, b.formatID(s.lastID), toggle.Event{Type: toggle.ResyncEvent}); err != nil {
+				return
+			}
+		}
+
+		for _, se := range s.backlog {
+			if err := writeServiceEvent(w, b, se, serviceName); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+
+		ticker := time.NewTicker(streamPingInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case se, open := <-s.ch:
+				if !open {
+					return
+				}
+				if err := writeServiceEvent(w, b, se, serviceName); err != nil {
+					return
+				}
+			case <-ticker.C:
+				if _, err := io.WriteString(w, ": ping\n\n"); err != nil {
+					return
+				}
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// writeServiceEvent writes the event with only the flags of the given service
+// and the global ones. Only the id is written if there are none, so that the
+// stream can be resumed after it.
+func writeServiceEvent(w io.Writer, b *broker, se streamEvent, serviceName string) error {
+	ev := toggle.Event{Type: se.event.Type, Error: se.event.Error}
+	for _, f := range se.event.Flags {
+		if f.ServiceName == serviceName || f.ServiceName == "" {
+			ev.Flags = append(ev.Flags, f)
+		}
+	}
+
+	if len(ev.Flags) == 0 {
+		_, err := fmt.Fprintf(w, "id: %s\n\n", b.formatID(se.id))
+		return err
+	}
+
+	return writeStreamEvent(w, b.formatID(se.id), ev)
+}
+
+func writeStreamEvent(w io.Writer, id string, ev toggle.Event) error {
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return fmt.Errorf("encoding event: %v", err)
+	}
+
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", id, ev.Type, b)
+	return err
+}
@@ -0,0 +1,215 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/globusdigital/feature-toggles/toggle"
+	gomock "github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBroker_subscribe(t *testing.T) {
+	b := newBroker()
+	for i := 0; i < streamBufferSize+2; i++ {
+		b.publish(toggle.Event{Type: toggle.SaveEvent})
+	}
+
+	tests := []struct {
+		name        string
+		lastEventID string
+		want        []uint64
+		resync      bool
+	}{
+		{name: "new"},
+		{name: "latest", lastEventID: b.formatID(streamBufferSize + 2)},
+		{name: "resume", lastEventID: b.formatID(streamBufferSize), want: []uint64{streamBufferSize + 1, streamBufferSize + 2}},
+		{name: "oldest", lastEventID: b.formatID(2), want: make([]uint64, streamBufferSize)},
+		{name: "evicted", lastEventID: b.formatID(1), resync: true},
+		{name: "future", lastEventID: b.formatID(streamBufferSize + 3), resync: true},
+		{name: "other epoch", lastEventID: fmt.Sprintf("%d-%d", b.epoch+1, streamBufferSize), resync: true},
+		{name: "invalid", lastEventID: "foo", resync: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := b.subscribe(tt.lastEventID)
+			defer b.unsubscribe(s.ch)
+
+			a := assert.New(t)
+			a.Equal(tt.resync, s.resync)
+			a.Equal(uint64(streamBufferSize+2), s.lastID)
+			a.Len(s.backlog, len(tt.want))
+			for i, id := range tt.want {
+				if id != 0 {
+					a.Equal(id, s.backlog[i].id)
+				}
+			}
+		})
+	}
+}
+
+func TestBroker_publish_slow(t *testing.T) {
+	b := newBroker()
+	s := b.subscribe("")
+
+	for i := 0; i < streamSubscriberBuffer+1; i++ {
+		b.publish(toggle.Event{Type: toggle.SaveEvent})
+	}
+
+	var count int
+	for range s.ch {
+		count++
+	}
+	assert.Equal(t, streamSubscriberBuffer, count)
+
+	b.unsubscribe(s.ch)
+}
+
+func TestHandler_stream(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store, bus := NewMockStore(ctrl), NewMockBus(ctrl)
+	store.EXPECT().Save(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().Return(nil)
+	store.EXPECT().Delete(gomock.Any(), gomock.Any()).AnyTimes().Return(nil)
+	bus.EXPECT().Send(gomock.Any(), gomock.Any()).AnyTimes().Return(nil)
+
+	ts := httptest.NewServer(Handler("/flags", store, bus))
+	defer ts.Close()
+
+	a := assert.New(t)
+
+	stream := func(lastEventID string) (*bufio.Reader, func()) {
+		r, err := http.NewRequest("GET", ts.URL+"/flags/svc1/stream", nil)
+		a.NoError(err)
+		if lastEventID != "" {
+			r.Header.Set("Last-Event-ID", lastEventID)
+		}
+
+		resp, err := http.DefaultClient.Do(r)
+		a.NoError(err)
+		a.Equal(200, resp.StatusCode)
+		a.Equal("text/event-stream", resp.Header.Get("Content-Type"))
+
+		return bufio.NewReader(resp.Body), func() { resp.Body.Close() }
+	}
+
+	next := func(r *bufio.Reader) []string {
+		var lines []string
+		for {
+			line, err := r.ReadString('\n')
+			a.NoError(err)
+			if line == "\n" {
+				return lines
+			}
+			lines = append(lines, strings.TrimSuffix(line, "\n"))
+		}
+	}
+
+	send := func(method, serviceName, body string) {
+		r, err := http.NewRequest(method, ts.URL+"/flags/"+serviceName, strings.NewReader(body))
+		a.NoError(err)
+		resp, err := http.DefaultClient.Do(r)
+		a.NoError(err)
+		resp.Body.Close()
+		a.Equal(204, resp.StatusCode)
+	}
+
+	r, closeStream := stream("")
+
+	send("POST", "svc2", `[{"name": "flag11", "service": "svc2", "raw": "1", "value": true}]`)
+	send("POST", "svc1", strFlags2)
+	send("DELETE", "svc1", `[{"name": "flag10", "service": "svc1"}]`)
+
+	other := next(r)
+	a.Len(other, 1, "only the id of other service events is sent")
+	a.True(strings.HasSuffix(other[0], "-1"), other[0])
+
+	save := next(r)
+	a.Len(save, 3)
+	a.True(strings.HasSuffix(save[0], "-2"), save[0])
+	a.Equal("event: save", save[1])
+	a.Equal(`data: {"type":"save","flags":[{"name":"flag10","service":"svc1","raw":"1","value":true,"cond":{}},{"name":"flag11","raw":"raw string","cond":{}}],"error":""}`, save[2])
+
+	del := next(r)
+	a.True(strings.HasSuffix(del[0], "-3"), del[0])
+	a.Equal("event: delete", del[1])
+	closeStream()
+
+	r, closeStream = stream(strings.TrimPrefix(save[0], "id: "))
+	a.Equal(del, next(r))
+	closeStream()
+
+	r, closeStream = stream("foo")
+	resync := next(r)
+	a.Equal("event: resync", resync[1])
+	a.Equal(del[0], resync[0])
+	closeStream()
+}
+
+// chanBus is an event bus receiving the events sent to it, as if they were
+// sent by another server replica
+type chanBus chan toggle.Event
+
+func (b chanBus) Send(ctx context.Context, event toggle.Event) error {
+	b <- event
+	return nil
+}
+
+func (b chanBus) Receiver(ctx context.Context) <-chan toggle.Event {
+	return b
+}
+
+func TestHandler_stream_bus(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := NewMockStore(ctrl)
+	store.EXPECT().Save(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().Return(nil)
+
+	bus := make(chanBus, 1)
+	ts := httptest.NewServer(Handler("/flags", store, bus))
+	defer ts.Close()
+	defer close(bus)
+
+	a := assert.New(t)
+
+	resp, err := http.Get(ts.URL + "/flags/svc1/stream")
+	a.NoError(err)
+	defer resp.Body.Close()
+
+	// Wait for the stream to be established
+	time.Sleep(50 * time.Millisecond)
+
+	// Flags saved through another replica
+	bus <- toggle.Event{Type: toggle.SaveEvent, Flags: []toggle.Flag{toggle.NewFlag("flag1", "svc1", "1")}}
+	bus <- toggle.Event{Type: toggle.ErrorEvent, Error: "ignored"}
+
+	// Flags saved through this replica are streamed from the bus
+	r, err := http.NewRequest("POST", ts.URL+"/flags/svc1", strings.NewReader(`[{"name": "flag2", "service": "svc1", "raw": "1", "value": true}]`))
+	a.NoError(err)
+	saved, err := http.DefaultClient.Do(r)
+	a.NoError(err)
+	saved.Body.Close()
+	a.Equal(204, saved.StatusCode)
+
+	br := bufio.NewReader(resp.Body)
+	var data []string
+	for len(data) < 2 {
+		line, err := br.ReadString('\n')
+		if !a.NoError(err) {
+			return
+		}
+		if strings.HasPrefix(line, "data: ") {
+			data = append(data, line)
+		}
+	}
+	a.Contains(data[0], `"name":"flag1"`)
+	a.Contains(data[1], `"name":"flag2"`)
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+
+	"github.com/globusdigital/feature-toggles/toggle"
+)
+
+var flagTypeConsts = map[toggle.FlagType]string{
+	toggle.StringFlag:   "toggle.StringFlag",
+	toggle.BoolFlag:     "toggle.BoolFlag",
+	toggle.IntFlag:      "toggle.IntFlag",
+	toggle.FloatFlag:    "toggle.FloatFlag",
+	toggle.DurationFlag: "toggle.DurationFlag",
+	toggle.JSONFlag:     "toggle.JSONFlag",
+}
+
+// reserved are the identifiers used by the generated accessors
+var reserved = map[string]bool{"ctx": true, "v": true, "opts": true, "f": true, "toggle": true, "time": true, "context": true}
+
+type genAttribute struct {
+	attribute
+	Ident, Param, GoType, Option string
+}
+
+type genFlag struct {
+	toggle.Definition
+	Ident, TypeConst string
+
+	// Getter is the client method, Result its result type and DefaultArg its
+	// default argument, if any
+	Getter, Result, DefaultArg string
+
+	Attributes []genAttribute
+}
+
+type genData struct {
+	Source, Package string
+	Time            bool
+
+	Attributes []genAttribute
+	Flags      []genFlag
+}
+
+// generate returns the formatted Go source of the spec package
+func generate(s spec, source string) ([]byte, error) {
+	data := genData{Source: source, Package: s.Package}
+
+	attrs := map[string]genAttribute{}
+	for _, a := range s.Attributes {
+		t := attributeTypes[a.Type]
+		ga := genAttribute{attribute: a, Ident: exported(a.Name), Param: param(a.Name), GoType: t.goType, Option: t.option}
+		attrs[a.Name] = ga
+		data.Attributes = append(data.Attributes, ga)
+	}
+
+	for _, f := range s.Flags {
+		gf := genFlag{Definition: f.definition(), TypeConst: flagTypeConsts[f.Type]}
+		gf.Ident = exported(gf.Name)
+		for _, name := range f.Attributes {
+			gf.Attributes = append(gf.Attributes, attrs[name])
+		}
+
+		switch f.Type {
+		case toggle.BoolFlag:
+			gf.Getter, gf.Result = "GetCtx", "bool"
+		case toggle.IntFlag:
+			gf.Getter, gf.Result, gf.DefaultArg = "GetIntCtx", "int64", "0"
+			if f.Default != "" {
+				v, _ := strconv.ParseInt(f.Default, 10, 64)
+				gf.DefaultArg = strconv.FormatInt(v, 10)
+			}
+		case toggle.FloatFlag:
+			gf.Getter, gf.Result, gf.DefaultArg = "GetFloatCtx", "float64", "0"
+			if f.Default != "" {
+				v, _ := strconv.ParseFloat(f.Default, 64)
+				gf.DefaultArg = strconv.FormatFloat(v, 'g', -1, 64)
+			}
+		case toggle.DurationFlag:
+			gf.Getter, gf.Result, gf.DefaultArg = "GetDurationCtx", "time.Duration", "0"
+			if f.Default != "" {
+				d, _ := time.ParseDuration(f.Default)
+				gf.DefaultArg = durationLiteral(d)
+			}
+			data.Time = true
+		case toggle.JSONFlag:
+			gf.Getter = "GetJSONCtx"
+		default:
+			gf.Getter, gf.Result = "GetRawCtx", "string"
+		}
+
+		data.Flags = append(data.Flags, gf)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("executing template: %v", err)
+	}
+
+	b, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting source: %v", err)
+	}
+
+	return b, nil
+}
+
+// exported converts a flag or attribute name, such as "new-checkout", to an
+// exported identifier, such as "NewCheckout". An empty string is returned if
+// the name doesn't start with a letter.
+func exported(name string) string {
+	parts := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(parts) == 0 || !unicode.IsLetter([]rune(parts[0])[0]) {
+		return ""
+	}
+
+	var sb strings.Builder
+	for _, p := range parts {
+		r := []rune(p)
+		sb.WriteRune(unicode.ToUpper(r[0]))
+		sb.WriteString(string(r[1:]))
+	}
+
+	return sb.String()
+}
+
+// param converts an attribute name to a parameter name
+func param(name string) string {
+	r := []rune(exported(name))
+	r[0] = unicode.ToLower(r[0])
+
+	p := string(r)
+	if token.IsKeyword(p) || reserved[p] {
+		p += "Value"
+	}
+
+	return p
+}
+
+func durationLiteral(d time.Duration) string {
+	units := []struct {
+		d    time.Duration
+		name string
+	}{{time.Hour, "Hour"}, {time.Minute, "Minute"}, {time.Second, "Second"}, {time.Millisecond, "Millisecond"}, {time.Microsecond, "Microsecond"}}
+	for _, u := range units {
+		if d%u.d == 0 {
+			return fmt.Sprintf("%d * time.%s", d/u.d, u.name)
+		}
+	}
+
+	return fmt.Sprintf("%d * time.Nanosecond", d)
+}
+
+var tmpl = template.Must(template.New("").Funcs(template.FuncMap{
+	"quote": strconv.Quote,
+	"comment": func(s string) string {
+		return strings.Join(strings.Fields(s), " ")
+	},
+}).Parse(`// Code generated by toggle-gen from {{.Source}}. DO NOT EDIT.
+
+// Package {{.Package}} provides typed accessors of the service flags.
+package {{.Package}}
+
+import (
+	"context"
+	{{- if .Time}}
+	"time"
+	{{- end}}
+
+	"github.com/globusdigital/feature-toggles/toggle"
+)
+
+// Names of the flags
+const (
+{{- range .Flags}}
+	{{.Ident}}Flag = {{quote .Name}}
+{{- end}}
+)
+
+// Definitions of the flags, registered in the toggle.DefaultRegistry
+var (
+{{- range .Flags}}
+	{{.Ident}}Definition = toggle.Define(toggle.Definition{
+		Name: {{.Ident}}Flag,
+		{{- if .TypeConst}}
+		Type: {{.TypeConst}},
+		{{- end}}
+		{{- if .Default}}
+		Default: {{quote .Default}},
+		{{- end}}
+		{{- if .Description}}
+		Description: {{quote .Description}},
+		{{- end}}
+		{{- if .Owner}}
+		Owner: {{quote .Owner}},
+		{{- end}}
+	})
+{{- end}}
+)
+{{range .Attributes}}
+// For{{.Ident}} sets the {{.Name}} condition value{{with .Description}}. {{comment .}}{{end}}
+func For{{.Ident}}(v {{.GoType}}) toggle.Option {
+	return toggle.{{.Option}}({{quote .Name}}, v)
+}
+{{end}}
+// Flags provides typed access to the flags. The zero value uses the
+// toggle.DefaultClient.
+type Flags struct {
+	Client *toggle.Client
+}
+
+func (f Flags) client() *toggle.Client {
+	if f.Client != nil {
+		return f.Client
+	}
+
+	return toggle.DefaultClient
+}
+{{range .Flags}}
+// {{.Ident}} returns the {{.Name}} flag value{{with .Description}}. {{comment .}}{{end}}
+{{- if .Result}}
+func (f Flags) {{.Ident}}(ctx context.Context{{range .Attributes}}, {{.Param}} {{.GoType}}{{end}}, opts ...toggle.Option) {{.Result}} {
+{{- else}}
+func (f Flags) {{.Ident}}(ctx context.Context, v interface{}{{range .Attributes}}, {{.Param}} {{.GoType}}{{end}}, opts ...toggle.Option) {
+{{- end}}
+	{{- if .Attributes}}
+	opts = append(opts[:len(opts):len(opts)]{{range .Attributes}}, toggle.{{.Option}}({{quote .Name}}, {{.Param}}){{end}})
+	{{- end}}
+	{{if .Result}}return {{end}}f.client().{{.Getter}}(ctx, {{.Ident}}Flag{{with .DefaultArg}}, {{.}}{{end}}{{if not .Result}}, v{{end}}, opts...)
+}
+{{end}}`))
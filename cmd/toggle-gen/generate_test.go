@@ -0,0 +1,90 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		spec, golden string
+	}{
+		{spec: "flags.yaml", golden: "flags.go.golden"},
+		{spec: "flags.json", golden: "flags_json.go.golden"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			a := assert.New(t)
+
+			s, err := readSpec(filepath.Join("testdata", tt.spec))
+			if !a.NoError(err) {
+				return
+			}
+
+			got, err := generate(s, tt.spec)
+			if !a.NoError(err) {
+				return
+			}
+
+			golden := filepath.Join("testdata", tt.golden)
+			if *update {
+				a.NoError(ioutil.WriteFile(golden, got, 0644))
+			}
+
+			want, err := ioutil.ReadFile(golden)
+			a.NoError(err)
+			a.Equal(string(want), string(got))
+		})
+	}
+}
+
+func TestSpec_Validate(t *testing.T) {
+	userID := attribute{Name: "userID", Type: "int"}
+
+	tests := []struct {
+		name    string
+		spec    spec
+		wantErr bool
+	}{
+		{name: "valid", spec: spec{Package: "flags", Attributes: []attribute{userID}, Flags: []flagSpec{{Name: "a", Type: "bool", Attributes: []string{"userID"}}}}},
+		{name: "no package", spec: spec{Flags: []flagSpec{{Name: "a"}}}, wantErr: true},
+		{name: "no flags", spec: spec{Package: "flags"}, wantErr: true},
+		{name: "unknown attribute type", spec: spec{Package: "flags", Attributes: []attribute{{Name: "userID", Type: "uint"}}, Flags: []flagSpec{{Name: "a"}}}, wantErr: true},
+		{name: "duplicate attribute", spec: spec{Package: "flags", Attributes: []attribute{userID, userID}, Flags: []flagSpec{{Name: "a"}}}, wantErr: true},
+		{name: "unknown flag type", spec: spec{Package: "flags", Flags: []flagSpec{{Name: "a", Type: "uint"}}}, wantErr: true},
+		{name: "invalid default", spec: spec{Package: "flags", Flags: []flagSpec{{Name: "a", Type: "int", Default: "many"}}}, wantErr: true},
+		{name: "unknown flag attribute", spec: spec{Package: "flags", Flags: []flagSpec{{Name: "a", Attributes: []string{"userID"}}}}, wantErr: true},
+		{name: "identifier clash", spec: spec{Package: "flags", Flags: []flagSpec{{Name: "new-checkout"}, {Name: "new_checkout"}}}, wantErr: true},
+		{name: "no identifier", spec: spec{Package: "flags", Flags: []flagSpec{{Name: "1st"}}}, wantErr: true},
+		{name: "reserved identifier", spec: spec{Package: "flags", Flags: []flagSpec{{Name: "client"}}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.spec.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestIdentifiers(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal("NewCheckout", exported("new-checkout"))
+	a.Equal("RequestTimeout", exported("request.timeout"))
+	a.Equal("UserID", exported("userID"))
+	a.Equal("", exported("1st"))
+
+	a.Equal("userID", param("userID"))
+	a.Equal("typeValue", param("type"))
+	a.Equal("ctxValue", param("ctx"))
+}
@@ -0,0 +1,62 @@
+// Command toggle-gen generates a Go package with typed accessors of the flags
+// declared in a YAML or JSON spec, such as:
+//
+//	package: flags
+//	attributes:
+//	  - name: userID
+//	    type: int
+//	flags:
+//	  - name: new-checkout
+//	    type: bool
+//	    default: "f"
+//	    description: Enables the new checkout
+//	    attributes: [userID]
+//
+// The flag definitions are registered in the toggle.DefaultRegistry, and
+// each flag accessor requires the attributes its conditions expect.
+//
+// Usage:
+//
+//	//go:generate toggle-gen -spec flags.yaml -out flags.go
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+var (
+	specPath = flag.String("spec", "flags.yaml", "the flag spec file, in YAML or JSON")
+	out      = flag.String("out", "", "the output file. Defaults to the standard output")
+	pkg      = flag.String("package", "", "the package name, overriding the spec one")
+)
+
+func main() {
+	flag.Parse()
+
+	s, err := readSpec(*specPath)
+	if err != nil {
+		log.Fatalf("Error reading spec: %v", err)
+	}
+
+	if *pkg != "" {
+		s.Package = *pkg
+	}
+
+	b, err := generate(s, filepath.Base(*specPath))
+	if err != nil {
+		log.Fatalf("Error generating code: %v", err)
+	}
+
+	if *out == "" {
+		_, _ = os.Stdout.Write(b)
+		return
+	}
+
+	if err := ioutil.WriteFile(*out, b, 0644); err != nil {
+		log.Fatalf("Error writing output: %v", err)
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/globusdigital/feature-toggles/toggle"
+	"gopkg.in/yaml.v3"
+)
+
+// spec describes the flags of a service, and the condition attributes they
+// expect
+type spec struct {
+	Package    string      `json:"package" yaml:"package"`
+	Attributes []attribute `json:"attributes" yaml:"attributes"`
+	Flags      []flagSpec  `json:"flags" yaml:"flags"`
+}
+
+type attribute struct {
+	Name string `json:"name" yaml:"name"`
+	// Type is one of int, float, bool, string or semver
+	Type        string `json:"type" yaml:"type"`
+	Description string `json:"description" yaml:"description"`
+}
+
+type flagSpec struct {
+	Name        string          `json:"name" yaml:"name"`
+	Type        toggle.FlagType `json:"type" yaml:"type"`
+	Default     string          `json:"default" yaml:"default"`
+	Description string          `json:"description" yaml:"description"`
+	Owner       string          `json:"owner" yaml:"owner"`
+
+	// Attributes are the names of the attributes the flag conditions expect,
+	// which become required parameters of its accessor
+	Attributes []string `json:"attributes" yaml:"attributes"`
+}
+
+var attributeTypes = map[string]struct{ goType, option string }{
+	"int":    {"int64", "ForInt"},
+	"float":  {"float64", "ForFloat"},
+	"bool":   {"bool", "ForBool"},
+	"string": {"string", "ForString"},
+	"semver": {"string", "ForSemver"},
+	"time":   {"time.Time", "ForTime"},
+}
+
+// readSpec reads a JSON or YAML spec, depending on the file extension
+func readSpec(path string) (spec, error) {
+	var s spec
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return s, fmt.Errorf("reading spec: %v", err)
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(b, &s)
+	} else {
+		err = yaml.Unmarshal(b, &s)
+	}
+	if err != nil {
+		return s, fmt.Errorf("decoding spec %s: %v", path, err)
+	}
+
+	return s, s.Validate()
+}
+
+// Validate checks that the flags and attributes are valid and unique, and
+// that the flags only expect declared attributes
+func (s spec) Validate() error {
+	if s.Package == "" {
+		return errors.New("missing package name")
+	}
+	if len(s.Flags) == 0 {
+		return errors.New("no flags")
+	}
+
+	attrs := map[string]bool{}
+	idents := map[string]string{}
+	for _, a := range s.Attributes {
+		if a.Name == "" {
+			return errors.New("attribute without a name")
+		}
+		if _, ok := attributeTypes[a.Type]; !ok {
+			return fmt.Errorf("attribute %s: unknown type %q", a.Name, a.Type)
+		}
+		if attrs[a.Name] {
+			return fmt.Errorf("attribute %s: duplicate", a.Name)
+		}
+		attrs[a.Name] = true
+
+		if err := uniqueIdent(idents, "For"+exported(a.Name), a.Name); err != nil {
+			return err
+		}
+	}
+
+	for _, f := range s.Flags {
+		d := f.definition()
+		if err := d.Validate(); err != nil {
+			return fmt.Errorf("flag %s: %v", f.Name, err)
+		}
+
+		if err := uniqueIdent(idents, exported(d.Name), f.Name); err != nil {
+			return err
+		}
+
+		for _, name := range f.Attributes {
+			if !attrs[name] {
+				return fmt.Errorf("flag %s: unknown attribute %s", f.Name, name)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (f flagSpec) definition() toggle.Definition {
+	return toggle.Definition{
+		Name:        f.Name,
+		Type:        f.Type,
+		Default:     f.Default,
+		Description: f.Description,
+		Owner:       f.Owner,
+	}.Normalized()
+}
+
+// reservedIdents are the field and methods of the generated Flags type, which
+// flags can't generate
+var reservedIdents = map[string]bool{"Client": true, "client": true}
+
+// uniqueIdent records the Go identifier generated for the name, and returns an
+// error if another name already generated it
+func uniqueIdent(idents map[string]string, ident, name string) error {
+	if ident == "" || ident == "For" {
+		return fmt.Errorf("%s: no identifier can be generated", name)
+	}
+	if reservedIdents[ident] {
+		return fmt.Errorf("%s: identifier %s is reserved", name, ident)
+	}
+
+	if other, ok := idents[ident]; ok {
+		return fmt.Errorf("%s: identifier %s is already generated for %s", name, ident, other)
+	}
+	idents[ident] = name
+
+	return nil
+}
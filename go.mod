@@ -1,6 +1,6 @@
 module github.com/globusdigital/feature-toggles
 
-go 1.13
+go 1.14
 
 require (
 	github.com/go-chi/chi v4.1.2+incompatible
@@ -19,4 +19,5 @@ require (
 	golang.org/x/sync v0.0.0-20210220032951-036812b2e83c // indirect
 	golang.org/x/text v0.3.7 // indirect
 	google.golang.org/protobuf v1.27.1 // indirect
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -55,7 +55,10 @@ func (s Nats) Receiver(ctx context.Context) <-chan toggle.Event {
 		defer close(ch)
 
 		sub, err := s.Conn.Subscribe(NatsSubject, func(ev toggle.Event) {
-			ch <- ev
+			select {
+			case ch <- ev:
+			case <-ctx.Done():
+			}
 		})
 		if err != nil {
 			ch <- toggle.Event{Type: toggle.ErrorEvent, Error: "subscribing to nats subject: " + err.Error()}
@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"reflect"
 	"sync"
 
 	"github.com/globusdigital/feature-toggles/toggle"
@@ -12,12 +13,14 @@ type flagKey struct {
 }
 
 type Mem struct {
-	data map[flagKey]toggle.Flag
-	mu   sync.RWMutex
+	data        map[flagKey]toggle.Flag
+	revisions   map[string]int64
+	definitions map[string][]toggle.Definition
+	mu          sync.RWMutex
 }
 
 func NewMem() *Mem {
-	return &Mem{data: map[flagKey]toggle.Flag{}}
+	return &Mem{data: map[flagKey]toggle.Flag{}, revisions: map[string]int64{}, definitions: map[string][]toggle.Definition{}}
 }
 
 func (s *Mem) Get(ctx context.Context, serviceName string) ([]toggle.Flag, error) {
@@ -37,6 +40,26 @@ func (s *Mem) Get(ctx context.Context, serviceName string) ([]toggle.Flag, error
 	return ret, nil
 }
 
+// Revision returns the revision of the flags visible to the service. It
+// increases with every change to the service or global flags. The revision of
+// all flags is returned for an empty service name.
+func (s *Mem) Revision(ctx context.Context, serviceName string) (int64, error) {
+	if ctx.Err() != nil {
+		return 0, ctx.Err()
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var rev int64
+	for name, r := range s.revisions {
+		if name == "" || name == serviceName || serviceName == "" {
+			rev += r
+		}
+	}
+	return rev, nil
+}
+
 func (s *Mem) Save(ctx context.Context, flags []toggle.Flag, initial bool) error {
 	if ctx.Err() != nil {
 		return ctx.Err()
@@ -47,9 +70,13 @@ func (s *Mem) Save(ctx context.Context, flags []toggle.Flag, initial bool) error
 
 	for _, f := range flags {
 		key := flagKey{f.Name, f.ServiceName}
-		if _, ok := s.data[key]; !initial || !ok {
-			s.data[key] = f
+		old, ok := s.data[key]
+		if initial && ok || ok && reflect.DeepEqual(old, f) {
+			continue
 		}
+
+		s.data[key] = f
+		s.revisions[f.ServiceName]++
 	}
 
 	return nil
@@ -65,8 +92,49 @@ func (s *Mem) Delete(ctx context.Context, flags []toggle.Flag) error {
 
 	for _, f := range flags {
 		key := flagKey{f.Name, f.ServiceName}
-		delete(s.data, key)
+		if _, ok := s.data[key]; ok {
+			delete(s.data, key)
+			s.revisions[f.ServiceName]++
+		}
+	}
+
+	return nil
+}
+
+// Definitions returns the flag definitions of the service, or of all services
+// for an empty service name
+func (s *Mem) Definitions(ctx context.Context, serviceName string) ([]toggle.Definition, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
 	}
 
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var ret []toggle.Definition
+	for name, defs := range s.definitions {
+		if name == serviceName || serviceName == "" {
+			ret = append(ret, defs...)
+		}
+	}
+	return ret, nil
+}
+
+// SaveDefinitions replaces the flag definitions of the service
+func (s *Mem) SaveDefinitions(ctx context.Context, serviceName string, defs []toggle.Definition) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(defs) == 0 {
+		delete(s.definitions, serviceName)
+		return nil
+	}
+
+	s.definitions[serviceName] = append([]toggle.Definition(nil), defs...)
+
 	return nil
 }
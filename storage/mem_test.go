@@ -162,6 +162,86 @@ func TestMem_Delete(t *testing.T) {
 	}
 }
 
+func TestMem_Revision(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	s := NewMem()
+
+	revisions := func() [3]int64 {
+		var revs [3]int64
+		for i, name := range []string{"", "svc1", "svc2"} {
+			rev, err := s.Revision(ctx, name)
+			a.NoError(err)
+			revs[i] = rev
+		}
+		return revs
+	}
+
+	a.Equal([3]int64{0, 0, 0}, revisions())
+
+	a.NoError(s.Save(ctx, initialData, false))
+	a.Equal([3]int64{5, 4, 3}, revisions())
+
+	a.NoError(s.Save(ctx, initialData[:1], false))
+	a.Equal([3]int64{5, 4, 3}, revisions(), "unchanged flags keep the revision")
+
+	a.NoError(s.Save(ctx, []toggle.Flag{{Name: "n2", ServiceName: "svc1", RawValue: "1", Value: true}}, true))
+	a.Equal([3]int64{5, 4, 3}, revisions(), "existing initial flags keep the revision")
+
+	a.NoError(s.Save(ctx, []toggle.Flag{{Name: "n2", ServiceName: "svc1", RawValue: "1", Value: true}}, false))
+	a.Equal([3]int64{6, 5, 3}, revisions())
+
+	a.NoError(s.Delete(ctx, initialData[3:4]))
+	a.Equal([3]int64{7, 6, 4}, revisions(), "global flags change all services")
+
+	a.NoError(s.Delete(ctx, initialData[3:4]))
+	a.Equal([3]int64{7, 6, 4}, revisions())
+
+	_, err := s.Revision(canceledCtx(), "svc1")
+	a.Error(err)
+}
+
+var definitions = []toggle.Definition{
+	{Name: "n1", ServiceName: "svc1", Type: toggle.IntFlag, Default: "4", Description: "Number of workers", Owner: "team1"},
+	{Name: "n2", ServiceName: "svc1", Type: toggle.BoolFlag},
+	{Name: "n1", ServiceName: "svc2", Description: "Theme"},
+}
+
+func TestMem_Definitions(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	s := NewMem()
+
+	defs, err := s.Definitions(ctx, "")
+	a.NoError(err)
+	a.Empty(defs)
+
+	a.NoError(s.SaveDefinitions(ctx, "svc1", definitions[:2]))
+	a.NoError(s.SaveDefinitions(ctx, "svc2", definitions[2:]))
+
+	defs, err = s.Definitions(ctx, "svc1")
+	a.NoError(err)
+	a.Equal(definitions[:2], defs)
+
+	defs, err = s.Definitions(ctx, "")
+	a.NoError(err)
+	a.ElementsMatch(definitions, defs)
+
+	a.NoError(s.SaveDefinitions(ctx, "svc1", definitions[1:2]))
+	defs, err = s.Definitions(ctx, "svc1")
+	a.NoError(err)
+	a.Equal(definitions[1:2], defs, "saving replaces the service definitions")
+
+	a.NoError(s.SaveDefinitions(ctx, "svc2", nil))
+	defs, err = s.Definitions(ctx, "svc2")
+	a.NoError(err)
+	a.Empty(defs)
+
+	_, err = s.Definitions(canceledCtx(), "svc1")
+	a.Error(err)
+	a.Error(s.SaveDefinitions(canceledCtx(), "svc1", nil))
+}
+
 func canceledCtx() context.Context {
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel()
@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/globusdigital/feature-toggles/toggle"
 	"go.mongodb.org/mongo-driver/bson"
@@ -11,11 +12,19 @@ import (
 	"go.mongodb.org/mongo-driver/x/mongo/driver/connstring"
 )
 
-const flagsCollection = "flags"
+const (
+	flagsCollection       = "flags"
+	revisionsCollection   = "revisions"
+	definitionsCollection = "definitions"
+)
 
 type Mongo struct {
 	client *mongo.Client
 	db     string
+
+	// transactions is set when the deployment is a replica set or a sharded
+	// cluster, which support transactions
+	transactions bool
 }
 
 type flag struct {
@@ -27,6 +36,30 @@ type flag struct {
 
 	Condition toggle.Condition `bson:"condition"`
 	Expr      string           `bson:"expr"`
+
+	Rules []toggle.Rule `bson:"rules,omitempty"`
+
+	Variants  []toggle.Variant `bson:"variants,omitempty"`
+	VariantBy string           `bson:"variantBy,omitempty"`
+
+	ActiveFrom  *time.Time `bson:"activeFrom,omitempty"`
+	ActiveUntil *time.Time `bson:"activeUntil,omitempty"`
+}
+
+type definition struct {
+	Name        string `bson:"name"`
+	ServiceName string `bson:"serviceName"`
+
+	Type    toggle.FlagType `bson:"type,omitempty"`
+	Default string          `bson:"default,omitempty"`
+
+	Description string `bson:"description,omitempty"`
+	Owner       string `bson:"owner,omitempty"`
+}
+
+type revision struct {
+	ServiceName string `bson:"_id"`
+	Revision    int64  `bson:"revision"`
 }
 
 func NewMongo(ctx context.Context, url string) (*Mongo, error) {
@@ -48,7 +81,28 @@ func NewMongo(ctx context.Context, url string) (*Mongo, error) {
 		return nil, fmt.Errorf("creating indices: %v", err)
 	}
 
-	return &Mongo{client, cs.Database}, nil
+	_, err = client.Database(cs.Database).Collection(definitionsCollection).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "serviceName", Value: 1}, {Key: "name", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating indices: %v", err)
+	}
+
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	err = client.Database("admin").RunCommand(ctx, bson.D{{Key: "isMaster", Value: 1}}).Decode(&hello)
+	if err != nil {
+		return nil, fmt.Errorf("getting server topology: %v", err)
+	}
+
+	return &Mongo{
+		client:       client,
+		db:           cs.Database,
+		transactions: hello.SetName != "" || hello.Msg == "isdbgrid",
+	}, nil
 }
 
 func (s *Mongo) Get(ctx context.Context, serviceName string) ([]toggle.Flag, error) {
@@ -71,29 +125,66 @@ func (s *Mongo) Get(ctx context.Context, serviceName string) ([]toggle.Flag, err
 		return nil, nil
 	}
 
+	// Normalizing converts the decoded condition lists to typed slices
 	ret := make([]toggle.Flag, len(flags))
 	for i := range flags {
-		ret[i] = toggle.Flag(flags[i])
+		ret[i] = toggle.Flag(flags[i]).Normalized()
 	}
 
 	return ret, nil
 }
 
+// Revision returns the revision of the flags visible to the service. It
+// increases with every change to the service or global flags. The revision of
+// all flags is returned for an empty service name.
+func (s *Mongo) Revision(ctx context.Context, serviceName string) (int64, error) {
+	coll := s.client.Database(s.db).Collection(revisionsCollection)
+	filter := bson.D{}
+	if serviceName != "" {
+		filter = bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: bson.A{"", serviceName}}}}}
+	}
+	c, err := coll.Find(ctx, filter)
+	if err != nil {
+		return 0, fmt.Errorf("getting revision data: %v", err)
+	}
+
+	var revisions []revision
+	if err := c.All(ctx, &revisions); err != nil {
+		return 0, fmt.Errorf("decoding revision data: %v", err)
+	}
+
+	var rev int64
+	for _, r := range revisions {
+		rev += r.Revision
+	}
+
+	return rev, nil
+}
+
 func (s *Mongo) Save(ctx context.Context, flags []toggle.Flag, initial bool) error {
+	return s.write(ctx, func(ctx context.Context) error {
+		return s.save(ctx, flags, initial)
+	})
+}
+
+func (s *Mongo) save(ctx context.Context, flags []toggle.Flag, initial bool) error {
 	coll := s.client.Database(s.db).Collection(flagsCollection)
 
 	models := make([]mongo.WriteModel, 0, len(flags))
+	var services []string
 	for _, f := range flags {
 		if initial {
 			res := coll.FindOne(ctx, bson.D{{"serviceName", f.ServiceName}, {"name", f.Name}}, options.FindOne().SetProjection(bson.D{{"_id", 1}}))
 			if res.Err() == mongo.ErrNoDocuments {
 				models = append(models, mongo.NewInsertOneModel().SetDocument(flag(f)))
+				services = append(services, f.ServiceName)
 			}
 		} else {
 			models = append(models, mongo.NewUpdateOneModel().
 				SetUpsert(true).
 				SetFilter(bson.D{{"serviceName", f.ServiceName}, {"name", f.Name}}).
 				SetUpdate(bson.D{{"$set", flag(f)}}))
+			services = append(services, f.ServiceName)
 		}
 	}
 
@@ -101,14 +192,25 @@ func (s *Mongo) Save(ctx context.Context, flags []toggle.Flag, initial bool) err
 		return nil
 	}
 
-	if _, err := coll.BulkWrite(ctx, models); err != nil {
+	res, err := coll.BulkWrite(ctx, models)
+	if err != nil {
 		return fmt.Errorf("writing flag data: %v", err)
 	}
 
-	return nil
+	if res.InsertedCount+res.UpsertedCount+res.ModifiedCount == 0 {
+		return nil
+	}
+
+	return s.increaseRevisions(ctx, services)
 }
 
 func (s *Mongo) Delete(ctx context.Context, flags []toggle.Flag) error {
+	return s.write(ctx, func(ctx context.Context) error {
+		return s.delete(ctx, flags)
+	})
+}
+
+func (s *Mongo) delete(ctx context.Context, flags []toggle.Flag) error {
 	coll := s.client.Database(s.db).Collection(flagsCollection)
 
 	models := make([]mongo.WriteModel, 0, len(flags))
@@ -122,9 +224,112 @@ func (s *Mongo) Delete(ctx context.Context, flags []toggle.Flag) error {
 		return nil
 	}
 
-	if _, err := coll.BulkWrite(ctx, models); err != nil {
+	res, err := coll.BulkWrite(ctx, models)
+	if err != nil {
 		return fmt.Errorf("deleting flag data: %v", err)
 	}
 
+	if res.DeletedCount == 0 {
+		return nil
+	}
+
+	services := make([]string, len(flags))
+	for i, f := range flags {
+		services[i] = f.ServiceName
+	}
+
+	return s.increaseRevisions(ctx, services)
+}
+
+// Definitions returns the flag definitions of the service, or of all services
+// for an empty service name
+func (s *Mongo) Definitions(ctx context.Context, serviceName string) ([]toggle.Definition, error) {
+	coll := s.client.Database(s.db).Collection(definitionsCollection)
+	filter := bson.D{}
+	if serviceName != "" {
+		filter = bson.D{{Key: "serviceName", Value: serviceName}}
+	}
+	c, err := coll.Find(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("getting definition data: %v", err)
+	}
+
+	var defs []definition
+	if err := c.All(ctx, &defs); err != nil {
+		return nil, fmt.Errorf("decoding definition data: %v", err)
+	}
+
+	if len(defs) == 0 {
+		return nil, nil
+	}
+
+	ret := make([]toggle.Definition, len(defs))
+	for i := range defs {
+		ret[i] = toggle.Definition(defs[i])
+	}
+
+	return ret, nil
+}
+
+// SaveDefinitions replaces the flag definitions of the service
+func (s *Mongo) SaveDefinitions(ctx context.Context, serviceName string, defs []toggle.Definition) error {
+	coll := s.client.Database(s.db).Collection(definitionsCollection)
+
+	models := make([]mongo.WriteModel, 0, len(defs)+1)
+	models = append(models, mongo.NewDeleteManyModel().SetFilter(bson.D{{Key: "serviceName", Value: serviceName}}))
+	for _, d := range defs {
+		models = append(models, mongo.NewInsertOneModel().SetDocument(definition(d)))
+	}
+
+	if _, err := coll.BulkWrite(ctx, models); err != nil {
+		return fmt.Errorf("writing definition data: %v", err)
+	}
+
+	return nil
+}
+
+// write runs fn in a transaction, so that a failed revision update also rolls
+// back the flag data. Standalone servers don't support transactions, and run
+// fn without one.
+func (s *Mongo) write(ctx context.Context, fn func(ctx context.Context) error) error {
+	if !s.transactions {
+		return fn(ctx)
+	}
+
+	sess, err := s.client.StartSession()
+	if err != nil {
+		return fmt.Errorf("starting session: %v", err)
+	}
+	defer sess.EndSession(ctx)
+
+	_, err = sess.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+
+	return err
+}
+
+// increaseRevisions increases the revision of each of the given services
+func (s *Mongo) increaseRevisions(ctx context.Context, services []string) error {
+	coll := s.client.Database(s.db).Collection(revisionsCollection)
+
+	seen := map[string]bool{}
+	models := make([]mongo.WriteModel, 0, len(services))
+	for _, name := range services {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		models = append(models, mongo.NewUpdateOneModel().
+			SetUpsert(true).
+			SetFilter(bson.D{{Key: "_id", Value: name}}).
+			SetUpdate(bson.D{{Key: "$inc", Value: bson.D{{Key: "revision", Value: 1}}}}))
+	}
+
+	if _, err := coll.BulkWrite(ctx, models); err != nil {
+		return fmt.Errorf("writing revision data: %v", err)
+	}
+
 	return nil
 }
@@ -169,8 +169,136 @@ func TestMongo_Delete(t *testing.T) {
 	}
 }
 
+func TestMongo_flagBSON(t *testing.T) {
+	activeFrom := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	activeUntil := time.Date(2021, 3, 3, 12, 30, 0, int(500*time.Millisecond), time.UTC)
+
+	tests := []struct {
+		name string
+		flag toggle.Flag
+	}{
+		{name: "simple", flag: initialData[0]},
+		{name: "bucket", flag: toggle.Flag{Name: "n1", ServiceName: "svc1", RawValue: "t", Value: true, Condition: toggle.Condition{
+			Fields: []toggle.ConditionField{{
+				ConditionValue: toggle.ConditionValue{Name: "userID", Type: toggle.IntType, Value: int64(25)},
+				Op:             toggle.LtOp,
+				Bucket:         &toggle.Bucket{Seed: "n1"},
+			}},
+		}}},
+		{name: "rules", flag: toggle.Flag{Name: "n1", ServiceName: "svc1", RawValue: "default", Rules: []toggle.Rule{
+			{Condition: toggle.Condition{Fields: []toggle.ConditionField{
+				{ConditionValue: toggle.ConditionValue{Name: "tenant", Type: toggle.StringType, Value: "beta"}},
+			}}, Expr: "tenant == 'beta'", RawValue: "beta", Value: true},
+			{Condition: toggle.Condition{Fields: []toggle.ConditionField{
+				{ConditionValue: toggle.ConditionValue{Name: "userID", Type: toggle.IntType, Value: int64(10)}, Op: toggle.LtOp},
+			}}, RawValue: "early", Value: true},
+		}}},
+		{name: "variants", flag: toggle.Flag{Name: "n1", ServiceName: "svc1", RawValue: "t", Value: true, Variants: []toggle.Variant{
+			{ConditionValue: toggle.ConditionValue{Name: "a", Type: toggle.StringType, Value: "blue"}, Weight: 80},
+			{ConditionValue: toggle.ConditionValue{Name: "b", Type: toggle.IntType, Value: int64(2)}, Weight: 20},
+		}, VariantBy: "userID"}},
+		{name: "lists", flag: toggle.Flag{Name: "n1", ServiceName: "svc1", RawValue: "t", Value: true, Condition: toggle.Condition{
+			Fields: []toggle.ConditionField{
+				{ConditionValue: toggle.ConditionValue{Name: "tenant", Type: toggle.StringType, Value: []string{"a", "b"}}, Op: toggle.InOp},
+				{ConditionValue: toggle.ConditionValue{Name: "userID", Type: toggle.IntType, Value: []int64{1, 2}}, Op: toggle.NotInOp},
+			},
+		}, Rules: []toggle.Rule{
+			{Condition: toggle.Condition{Fields: []toggle.ConditionField{
+				{ConditionValue: toggle.ConditionValue{Name: "ratio", Type: toggle.FloatType, Value: []float64{0.5}}, Op: toggle.InOp},
+			}}, RawValue: "half"},
+		}}},
+		{name: "not", flag: toggle.Flag{Name: "n1", ServiceName: "svc1", RawValue: "t", Value: true, Condition: toggle.Condition{
+			Conditions: []toggle.Condition{{Op: toggle.OrOp, Not: true, Fields: []toggle.ConditionField{
+				{ConditionValue: toggle.ConditionValue{Name: "version", Type: toggle.IntType, Value: int64(3)}, Op: toggle.GeOp},
+				{ConditionValue: toggle.ConditionValue{Name: "ratio", Type: toggle.FloatType, Value: 0.5}, Op: toggle.LeOp},
+			}}},
+		}}},
+		{name: "time", flag: toggle.Flag{Name: "n1", ServiceName: "svc1", RawValue: "t", Value: true, Condition: toggle.Condition{
+			Fields: []toggle.ConditionField{
+				{ConditionValue: toggle.ConditionValue{Name: "now", Type: toggle.TimeType, Value: time.Date(2021, 3, 1, 9, 0, 0, 0, time.UTC)}, Op: toggle.GeOp},
+			},
+		}, ActiveFrom: &activeFrom, ActiveUntil: &activeUntil}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := assert.New(t)
+
+			b, err := bson.Marshal(flag(tt.flag))
+			a.NoError(err)
+
+			var got flag
+			a.NoError(bson.Unmarshal(b, &got))
+			a.Equal(tt.flag, toggle.Flag(got).Normalized())
+		})
+	}
+}
+
 var mongoURL = "mongodb://localhost:27017/"
 
+func TestMongo_Revision(t *testing.T) {
+	url, cleanup := getTempDB(t)
+	defer cleanup()
+
+	a := assert.New(t)
+	ctx := context.Background()
+	s, err := NewMongo(ctx, url)
+	a.NoError(err)
+
+	revisions := func() [3]int64 {
+		var revs [3]int64
+		for i, name := range []string{"", "svc1", "svc2"} {
+			rev, err := s.Revision(ctx, name)
+			a.NoError(err)
+			revs[i] = rev
+		}
+		return revs
+	}
+
+	a.Equal([3]int64{0, 0, 0}, revisions())
+
+	a.NoError(s.Save(ctx, initialData, false))
+	a.Equal([3]int64{3, 2, 2}, revisions())
+
+	a.NoError(s.Save(ctx, initialData[:1], false))
+	a.Equal([3]int64{3, 2, 2}, revisions(), "unchanged flags keep the revision")
+
+	a.NoError(s.Save(ctx, []toggle.Flag{{Name: "n2", ServiceName: "svc1", RawValue: "1", Value: true}}, true))
+	a.Equal([3]int64{3, 2, 2}, revisions(), "existing initial flags keep the revision")
+
+	a.NoError(s.Delete(ctx, initialData[3:4]))
+	a.Equal([3]int64{4, 3, 3}, revisions(), "global flags change all services")
+}
+
+func TestMongo_Definitions(t *testing.T) {
+	url, cleanup := getTempDB(t)
+	defer cleanup()
+
+	a := assert.New(t)
+	ctx := context.Background()
+	s, err := NewMongo(ctx, url)
+	a.NoError(err)
+
+	defs, err := s.Definitions(ctx, "")
+	a.NoError(err)
+	a.Empty(defs)
+
+	a.NoError(s.SaveDefinitions(ctx, "svc1", definitions[:2]))
+	a.NoError(s.SaveDefinitions(ctx, "svc2", definitions[2:]))
+
+	defs, err = s.Definitions(ctx, "svc1")
+	a.NoError(err)
+	a.ElementsMatch(definitions[:2], defs)
+
+	defs, err = s.Definitions(ctx, "")
+	a.NoError(err)
+	a.ElementsMatch(definitions, defs)
+
+	a.NoError(s.SaveDefinitions(ctx, "svc1", definitions[1:2]))
+	defs, err = s.Definitions(ctx, "svc1")
+	a.NoError(err)
+	a.Equal(definitions[1:2], defs, "saving replaces the service definitions")
+}
+
 func getTempDB(t *testing.T) (string, func()) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
 	defer cancel()
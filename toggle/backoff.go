@@ -0,0 +1,71 @@
+package toggle
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Backoff describes the delays between the retries of failed server requests.
+// The delay grows exponentially from the initial delay up to the maximum one,
+// and is randomly reduced by the jitter fraction, so that clients restarted
+// together don't retry in lockstep.
+type Backoff struct {
+	// Initial is the delay before the first retry, or the initial delay of
+	// the DefaultBackoff if not positive
+	Initial time.Duration
+	// Max is the maximum delay, ignored if not positive
+	Max time.Duration
+	// Multiplier is the factor by which the delay grows after each retry.
+	// Values below 1 are treated as 1.
+	Multiplier float64
+	// Jitter is the fraction of the delay which is randomized, between 0 and 1
+	Jitter float64
+	// MaxRetries is the number of consecutive retries after which the client
+	// gives up, or 0 for retrying indefinitely. When giving up, the seed is
+	// abandoned and the client stops connecting, while failed polls are
+	// retried at the polling interval.
+	MaxRetries int
+}
+
+// DefaultBackoff is the backoff policy used by clients unless configured
+// otherwise
+var DefaultBackoff = Backoff{
+	Initial:    time.Second,
+	Max:        5 * time.Minute,
+	Multiplier: 2,
+	Jitter:     0.2,
+}
+
+// Delay returns the delay before the given retry, starting from 0. Delays
+// exceeding the duration range are capped to the longest duration.
+func (b Backoff) Delay(retry int) time.Duration {
+	initial := b.Initial
+	if initial <= 0 {
+		initial = DefaultBackoff.Initial
+	}
+
+	multiplier := math.Max(b.Multiplier, 1)
+	d := float64(initial) * math.Pow(multiplier, float64(retry))
+	if b.Max > 0 && d > float64(b.Max) {
+		d = float64(b.Max)
+	}
+	// The conversion of an out of range float is implementation specific,
+	// and an infinite delay can't be jittered
+	d = math.Min(d, math.MaxInt64)
+
+	jitter := math.Min(math.Max(b.Jitter, 0), 1)
+	d -= d * jitter * rand.Float64()
+
+	if d >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+
+	return time.Duration(d)
+}
+
+// exhausted reports whether no more retries are allowed after the given number
+// of retries
+func (b Backoff) exhausted(retries int) bool {
+	return b.MaxRetries > 0 && retries >= b.MaxRetries
+}
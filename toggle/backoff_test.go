@@ -0,0 +1,60 @@
+package toggle_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/globusdigital/feature-toggles/toggle"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoff_Delay(t *testing.T) {
+	tests := []struct {
+		name    string
+		backoff toggle.Backoff
+		retry   int
+		want    time.Duration
+	}{
+		{name: "initial", backoff: toggle.Backoff{Initial: time.Second, Multiplier: 2}, want: time.Second},
+		{name: "exponential", backoff: toggle.Backoff{Initial: time.Second, Multiplier: 2}, retry: 3, want: 8 * time.Second},
+		{name: "max", backoff: toggle.Backoff{Initial: time.Second, Multiplier: 2, Max: 5 * time.Second}, retry: 3, want: 5 * time.Second},
+		{name: "max overflow", backoff: toggle.Backoff{Initial: time.Second, Multiplier: 2, Max: 5 * time.Second}, retry: 5000, want: 5 * time.Second},
+		{name: "constant", backoff: toggle.Backoff{Initial: time.Second}, retry: 3, want: time.Second},
+		{name: "overflow", backoff: toggle.Backoff{Initial: time.Second, Multiplier: 2}, retry: 70, want: math.MaxInt64},
+		{name: "infinite", backoff: toggle.Backoff{Initial: time.Second, Multiplier: 2}, retry: 5000, want: math.MaxInt64},
+		{name: "zero initial", backoff: toggle.Backoff{Multiplier: 2}, retry: 1, want: 2 * time.Second},
+		{name: "negative initial", backoff: toggle.Backoff{Initial: -time.Second}, want: time.Second},
+		{name: "invalid multiplier", backoff: toggle.Backoff{Initial: time.Second, Multiplier: 0.5}, retry: 3, want: time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.backoff.Delay(tt.retry))
+		})
+	}
+}
+
+func TestBackoff_Delay_jitter(t *testing.T) {
+	a := assert.New(t)
+
+	b := toggle.Backoff{Initial: time.Second, Multiplier: 2, Jitter: 0.2}
+	delays := map[time.Duration]bool{}
+	for i := 0; i < 100; i++ {
+		d := b.Delay(1)
+		a.True(d > 1600*time.Millisecond && d <= 2*time.Second, d)
+		delays[d] = true
+	}
+	a.True(len(delays) > 1)
+
+	b.Jitter = 5
+	for i := 0; i < 100; i++ {
+		d := b.Delay(0)
+		a.True(d >= 0 && d <= time.Second, d)
+	}
+
+	b.Jitter = 0.2
+	for i := 0; i < 100; i++ {
+		d := b.Delay(1000)
+		a.True(d > 0, d)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -12,8 +13,10 @@ import (
 	"os"
 	"path"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 	"unicode"
 )
@@ -21,14 +24,62 @@ import (
 const (
 	featurePrefix = "FEATURE_"
 	globalName    = "_GLOBAL__"
+
+	errorBufferSize = 16
 )
 
 type Client struct {
 	name string
 	opts clientOptions
 
-	store map[string][]Flag
-	mu    sync.RWMutex
+	// store is modified while holding the lock, and published to current as
+	// an immutable copy which is read without locking
+	store   map[string][]entry
+	current atomic.Value
+	mu      sync.RWMutex
+
+	// etag is the revision of the last flags obtained from the server
+	etag string
+
+	// env holds the environment flags, which are seeded even if they were
+	// replaced in the store by snapshot flags
+	env []Flag
+
+	listeners  map[int]listener
+	listenerID int
+	lmu        sync.Mutex
+
+	lifecycle *lifecycle
+}
+
+// entry is a stored flag along with the source it was obtained from, and its
+// compiled condition and rule conditions
+type entry struct {
+	Flag
+	source Source
+
+	match   matchFunc
+	explain explainFunc
+	rules   []matchFunc
+
+	// timed is set when the flag has an activation window, or its conditions
+	// use the current time
+	timed bool
+}
+
+// newEntry creates an entry for the flag, compiling its conditions
+func newEntry(f Flag, source Source) entry {
+	e := entry{Flag: f, source: source, match: f.Condition.compile(), explain: f.Condition.compileExplain()}
+	e.timed = f.ActiveFrom != nil || f.ActiveUntil != nil || f.Condition.uses(NowValue)
+	if len(f.Rules) > 0 {
+		e.rules = make([]matchFunc, len(f.Rules))
+		for i, r := range f.Rules {
+			e.rules[i] = r.Condition.compile()
+			e.timed = e.timed || r.Condition.uses(NowValue)
+		}
+	}
+
+	return e
 }
 
 type Flag struct {
@@ -40,6 +91,22 @@ type Flag struct {
 
 	Condition Condition `json:"cond,omitempty"`
 	Expr      string    `json:"expr,omitempty"`
+
+	// Rules are evaluated in order, with the first matching rule overriding
+	// the flag value. The flag value is used if none of them match.
+	Rules []Rule `json:"rules,omitempty"`
+
+	// Variants holds the weighted values of a multivariate flag
+	Variants []Variant `json:"variants,omitempty"`
+	// VariantBy is the name of the condition value used for assigning
+	// variants. All condition values are used when empty.
+	VariantBy string `json:"variantBy,omitempty"`
+
+	// ActiveFrom and ActiveUntil limit the flag to the activation window
+	// starting at ActiveFrom, and ending before ActiveUntil. The window is
+	// open ended on the sides which aren't set.
+	ActiveFrom  *time.Time `json:"activeFrom,omitempty"`
+	ActiveUntil *time.Time `json:"activeUntil,omitempty"`
 }
 
 func (f *Flag) UnmarshalJSON(d []byte) error {
@@ -59,6 +126,24 @@ func (f *Flag) UnmarshalJSON(d []byte) error {
 	return err
 }
 
+// NewFlag creates a flag with the given raw value, whose boolean value is true
+// for raw values such as "1", "y", "yes", "t" and "true". An empty service name
+// denotes a global flag.
+func NewFlag(name, serviceName, rawValue string) Flag {
+	var value bool
+	switch strings.ToLower(rawValue) {
+	case "1", "y", "yes", "t", "true":
+		value = true
+	}
+
+	return Flag{
+		Name:        NormalizeName(name),
+		ServiceName: normalizeSerivceName(serviceName),
+		RawValue:    rawValue,
+		Value:       value,
+	}
+}
+
 // New creates a new toggle client with the given service name
 func New(name string, opts ...ClientOption) *Client {
 	name = normalizeSerivceName(name)
@@ -69,9 +154,16 @@ func New(name string, opts ...ClientOption) *Client {
 		httpClient:     http.DefaultClient,
 		log:            log.New(os.Stderr, "", log.LstdFlags|log.Lshortfile),
 		path:           "/flags",
+		snapshotMaxAge: 24 * time.Hour,
+		backoff:        DefaultBackoff,
+		registry:       DefaultRegistry,
+		clock:          time.Now,
 	}).Apply(opts)
 
-	return &Client{name: name, opts: o, store: map[string][]Flag{}}
+	c := &Client{name: name, opts: o, store: map[string][]entry{}, lifecycle: newLifecycle()}
+	c.publish()
+
+	return c
 }
 
 // Get returns the boolean flag value
@@ -92,32 +184,116 @@ func (c *Client) GetRaw(name string, opts ...Option) string {
 	return f.RawValue
 }
 
-func (c *Client) getFlag(name string, o getOptions) Flag {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+// GetInt returns the flag value parsed as an integer. The default is returned
+// if the flag is missing or its value cannot be parsed.
+func (c *Client) GetInt(name string, def int64, opts ...Option) int64 {
+	raw := c.GetRaw(name, opts...)
+	if raw == "" {
+		return def
+	}
 
-	name = normalizeName(name)
-	for _, f := range c.store[name] {
-		if f.ServiceName != c.name && (f.ServiceName != "" || !o.global) {
-			continue
-		}
+	v, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		c.opts.log.Printf("Error parsing flag %s value %q as int: %v", name, raw, err)
+		return def
+	}
 
-		values := make([]ConditionValue, len(c.opts.values)+len(o.values))
-		copy(values, c.opts.values)
-		copy(values[len(c.opts.values):], o.values)
-		if !f.Condition.Match(values) {
-			break
+	return v
+}
+
+// GetFloat returns the flag value parsed as a float. The default is returned
+// if the flag is missing or its value cannot be parsed.
+func (c *Client) GetFloat(name string, def float64, opts ...Option) float64 {
+	raw := c.GetRaw(name, opts...)
+	if raw == "" {
+		return def
+	}
+
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		c.opts.log.Printf("Error parsing flag %s value %q as float: %v", name, raw, err)
+		return def
+	}
+
+	return v
+}
+
+// GetDuration returns the flag value parsed as a duration, such as "1m30s".
+// The default is returned if the flag is missing or its value cannot be
+// parsed.
+func (c *Client) GetDuration(name string, def time.Duration, opts ...Option) time.Duration {
+	raw := c.GetRaw(name, opts...)
+	if raw == "" {
+		return def
+	}
+
+	v, err := time.ParseDuration(raw)
+	if err != nil {
+		c.opts.log.Printf("Error parsing flag %s value %q as duration: %v", name, raw, err)
+		return def
+	}
+
+	return v
+}
+
+// GetJSON unmarshals the raw flag value into v, which acts as the default.
+// It is left untouched if the flag is missing or its value isn't valid JSON.
+func (c *Client) GetJSON(name string, v interface{}, opts ...Option) {
+	raw := c.GetRaw(name, opts...)
+	if raw == "" {
+		return
+	}
+
+	if err := json.Unmarshal([]byte(raw), v); err != nil {
+		c.opts.log.Printf("Error parsing flag %s value %q as json: %v", name, raw, err)
+	}
+}
+
+// Variant returns the variant of a multivariate flag, deterministically
+// assigned from the condition values. A zero Variant is returned if the flag
+// doesn't exist or has no variants.
+func (c *Client) Variant(name string, opts ...Option) Variant {
+	o := (getOptions{}).Apply(opts)
+
+	d := c.evaluate(name, o)
+	switch {
+	case d.Reason == OverriddenReason:
+		for _, v := range d.Flag.Variants {
+			if v.Name == d.RawValue {
+				return v
+			}
 		}
+		return Variant{}
+	case !d.Reason.matched():
+		return Variant{}
+	}
+
+	return d.Flag.variant(c.values(o))
+}
 
-		return f
+// getFlag returns the matching flag, with its value resolved by its rules
+func (c *Client) getFlag(name string, o getOptions) Flag {
+	d := c.evaluate(name, o)
+	if !d.Reason.matched() && d.Reason != DefaultReason {
+		return Flag{}
 	}
 
-	return Flag{}
+	f := d.Flag
+	f.RawValue, f.Value = d.RawValue, d.Value
+
+	return f
+}
+
+// values returns the condition values for an evaluation. Context values take
+// precedence over the client ones, and call values over both.
+func (c *Client) values(o getOptions) []ConditionValue {
+	return mergeValues(mergeValues(c.opts.values, o.ctxValues), o.values)
 }
 
 // ParseEnv parses the given environment variables and populates the flags
 func (c *Client) ParseEnv(env []string) {
-	flags := map[string][]Flag{}
+	flags := map[string][]entry{}
+	var envFlags []Flag
 
 	for _, e := range env {
 		if !strings.HasPrefix(e, featurePrefix) {
@@ -140,83 +316,190 @@ func (c *Client) ParseEnv(env []string) {
 			key = key[underIdx+1:]
 		}
 
-		var value bool
-		switch strings.ToLower(rawValue) {
-		case "1", "y", "yes", "t", "true":
-			value = true
+		f := NewFlag(key, serviceName, rawValue)
+		if f.ServiceName != c.name && f.ServiceName != "" {
+			continue
 		}
 
-		key = normalizeName(key)
-		serviceName = normalizeSerivceName(serviceName)
+		flags[f.Name] = append(flags[f.Name], newEntry(f, EnvSource))
+		envFlags = append(envFlags, f)
+	}
+
+	c.modifyStore(func() {
+		c.store, c.env = flags, envFlags
+	})
+}
 
-		if serviceName != c.name && serviceName != "" {
-			continue
+// SetFlags stores the given flags, replacing the ones with the same name and
+// service. Flags for other services are ignored. The flags are replaced by the
+// server flags on the next seed or poll, even if the server flags weren't
+// modified since the previous poll.
+func (c *Client) SetFlags(flags ...Flag) {
+	c.Override(flags...)
+}
+
+// Override stores the given flags like SetFlags, and returns a function which
+// restores the flags they replaced
+func (c *Client) Override(flags ...Flag) (restore func()) {
+	type key struct{ name, serviceName string }
+	previous := map[key]*entry{}
+	var order []key
+
+	c.modifyStore(func() {
+		for _, f := range flags {
+			f = f.Normalized()
+			if f.ServiceName != c.name && f.ServiceName != "" {
+				continue
+			}
+
+			k := key{f.Name, f.ServiceName}
+			if _, ok := previous[k]; !ok {
+				order = append(order, k)
+				previous[k] = nil
+				if e, ok := c.removeEntry(f.Name, f.ServiceName); ok {
+					previous[k] = &e
+				}
+			}
+
+			c.setEntry(newEntry(f, ManualSource))
 		}
 
-		flags[key] = append(flags[key], Flag{
-			Name:        key,
-			ServiceName: serviceName,
-			RawValue:    rawValue,
-			Value:       value,
+		// The next poll gets all server flags, even if they weren't modified
+		c.etag = ""
+	})
+
+	return func() {
+		c.modifyStore(func() {
+			for _, k := range order {
+				c.removeEntry(k.name, k.serviceName)
+				if e := previous[k]; e != nil {
+					c.setEntry(*e)
+				}
+			}
 		})
 	}
-
-	c.mu.Lock()
-	c.store = flags
-	c.mu.Unlock()
 }
 
+// Connect sends the environment flags to the server, and keeps the flags up to
+// date using the event bus and polling. Errors are sent to the returned
+// channel, which is buffered and closed when the context is canceled, the
+// client is closed or it gives up. Errors are dropped when the buffer is full.
 func (c *Client) Connect(ctx context.Context) chan error {
-	errC := make(chan error)
+	errC := make(chan error, errorBufferSize)
+
+	ctx, cancel := context.WithCancel(ctx)
+	c.lifecycle.start(cancel, errC)
 
 	go func() {
 		defer close(errC)
+		defer cancel()
 
 		addr := c.GetRaw(ServerAddressFlag, Global)
 		if addr == "" {
+			c.lifecycle.markReady()
+			c.lifecycle.stop(IdleState)
 			return
 		}
 
-		backoff := time.Duration(1)
-		for {
-			if err := c.seedFlags(ctx, addr); err != nil {
-				errC <- err
+		// The final state depends on whether the client gave up
+		state := ClosedState
+		defer func() {
+			c.lifecycle.stop(state)
+		}()
 
-				retry := time.Second * backoff
-				c.opts.log.Printf("Error sending the seed flags: %v. Retry in %s", err, retry)
-				backoff++
+		c.lifecycle.setState(ConnectingState)
 
-				select {
-				case <-ctx.Done():
-					return
-				case <-time.After(retry):
-					continue
-				}
+		if loaded, err := c.loadSnapshot(); err != nil {
+			c.opts.log.Println("Error loading flag snapshot:", err)
+		} else if loaded {
+			c.lifecycle.markReady()
+		}
+
+		for retries := 0; ; retries++ {
+			err := c.seedFlags(ctx, addr)
+			if err == nil {
+				break
+			}
+
+			if c.opts.backoff.exhausted(retries) {
+				c.reportError(errC, fmt.Errorf("giving up after %d retries: %v", retries, err))
+				state = DisconnectedState
+				return
+			}
+			c.reportError(errC, err)
+
+			retry := c.opts.backoff.Delay(retries)
+			c.opts.log.Printf("Error sending the seed flags: %v. Retry in %s", err, retry)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(retry):
 			}
+		}
+		c.lifecycle.synced()
+		c.lifecycle.markReady()
+		c.saveSnapshot()
 
-			break
+		bus := c.opts.eventBus
+		if c.opts.serverEvents {
+			bus = NewSSEBus(addr+path.Join(c.opts.path, c.name, "stream"), c.opts.httpClient)
 		}
 
 		var ch <-chan Event
-		if c.opts.eventBus != nil {
-			ch = c.opts.eventBus.Receiver(ctx)
+		if bus != nil {
+			ch = bus.Receiver(ctx)
+		}
+
+		// poll returns the delay until the next poll, which backs off after
+		// failures without exceeding the polling interval
+		var pollRetries int
+		poll := func() time.Duration {
+			updated, err := c.pollFlags(ctx, addr)
+			if err != nil {
+				c.reportError(errC, err)
+
+				if c.opts.backoff.exhausted(pollRetries) {
+					return c.opts.updateDuration
+				}
+
+				retry := c.opts.backoff.Delay(pollRetries)
+				pollRetries++
+				if retry > c.opts.updateDuration {
+					retry = c.opts.updateDuration
+				}
+				return retry
+			}
+
+			pollRetries = 0
+			c.lifecycle.synced()
+			if updated {
+				c.saveSnapshot()
+			}
+			return c.opts.updateDuration
 		}
 
-		ticker := time.NewTicker(c.opts.updateDuration)
+		timer := time.NewTimer(c.opts.updateDuration)
 		for {
 			select {
 			case <-ctx.Done():
-				ticker.Stop()
+				timer.Stop()
 				return
 			case ev, open := <-ch:
 				if !open {
 					ch = nil
 				}
+				if ev.Type == ResyncEvent {
+					resetTimer(timer, poll())
+					continue
+				}
+
 				c.processEvent(ev)
-			case <-ticker.C:
-				if err := c.pollFlags(ctx, addr); err != nil {
-					errC <- err
+				if ev.Type == SaveEvent || ev.Type == DeleteEvent {
+					c.saveSnapshot()
 				}
+			case <-timer.C:
+				timer.Reset(poll())
 			}
 		}
 	}()
@@ -224,6 +507,29 @@ func (c *Client) Connect(ctx context.Context) chan error {
 	return errC
 }
 
+// reportError records the error in the client status and sends it to the
+// channel, without blocking if it is full
+func (c *Client) reportError(errC chan error, err error) {
+	c.lifecycle.failed(err)
+
+	select {
+	case errC <- err:
+	default:
+		c.opts.log.Println("Dropping unread client error:", err)
+	}
+}
+
+// resetTimer resets a timer which may have already fired
+func resetTimer(t *time.Timer, d time.Duration) {
+	if !t.Stop() {
+		select {
+		case <-t.C:
+		default:
+		}
+	}
+	t.Reset(d)
+}
+
 func (c *Client) MarshalJSON() ([]byte, error) {
 	type jsonClient struct {
 		Opts struct {
@@ -237,11 +543,10 @@ func (c *Client) MarshalJSON() ([]byte, error) {
 	client.Opts.Path = c.opts.path
 	client.Opts.Values = c.opts.values
 
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	for _, flags := range c.store {
-		client.Flags = append(client.Flags, flags...)
+	for _, entries := range c.loadStore() {
+		for _, e := range entries {
+			client.Flags = append(client.Flags, e.Flag)
+		}
 	}
 	sort.Slice(client.Flags, func(i, j int) bool {
 		if client.Flags[i].Name == client.Flags[j].Name {
@@ -259,12 +564,14 @@ func (c *Client) seedFlags(ctx context.Context, addr string) error {
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	c.mu.RLock()
-	data := make([]Flag, 0, len(c.store))
-
-	for _, flags := range c.store {
-		data = append(data, flags...)
+	if err := c.sendDefinitions(ctx, addr); err != nil {
+		return err
 	}
+
+	// Only the environment flags are seeded, snapshot flags may already be
+	// deleted on the server
+	c.mu.RLock()
+	data := append(make([]Flag, 0, len(c.env)), c.env...)
 	c.mu.RUnlock()
 
 	b, err := json.Marshal(data)
@@ -288,28 +595,94 @@ func (c *Client) seedFlags(ctx context.Context, addr string) error {
 		return fmt.Errorf("invalid status code for %s: %d (%s)", cleanupURL(r.URL), resp.StatusCode, resp.Status)
 	}
 
-	return c.updateStore(resp.Body)
+	if err := c.updateStore(resp.Body, SeedSource); err != nil {
+		return err
+	}
+	c.setETag(resp.Header.Get("ETag"))
+
+	return nil
+}
+
+// sendDefinitions sends the flag definitions of the registry to the server,
+// replacing the ones it holds for the service. Servers without the definitions
+// endpoint are ignored.
+func (c *Client) sendDefinitions(ctx context.Context, addr string) error {
+	defs := c.opts.registry.Definitions()
+	if len(defs) == 0 {
+		return nil
+	}
+
+	for i := range defs {
+		defs[i].ServiceName = c.name
+	}
+
+	b, err := json.Marshal(defs)
+	if err != nil {
+		return fmt.Errorf("encoding flag definitions: %v", err)
+	}
+
+	r, err := http.NewRequestWithContext(ctx, "POST", addr+path.Join(c.opts.path, c.name, "definitions"), bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("creating flag definitions request: %v", err)
+	}
+	r.Header.Add("Content-Type", "application/json")
+
+	resp, err := c.opts.httpClient.Do(r)
+	if err != nil {
+		return fmt.Errorf("getting flag definitions response: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("invalid status code for %s: %d (%s)", cleanupURL(r.URL), resp.StatusCode, resp.Status)
+	}
+
+	return nil
 }
 
-func (c *Client) pollFlags(ctx context.Context, addr string) error {
+// pollFlags reports whether the store was updated with the polled flags, which
+// isn't the case if they weren't modified since the last poll
+func (c *Client) pollFlags(ctx context.Context, addr string) (bool, error) {
 	c.opts.log.Println("Polling for flags")
 
 	r, err := http.NewRequestWithContext(ctx, "GET", addr+path.Join(c.opts.path, c.name), nil)
 	if err != nil {
-		return fmt.Errorf("creating update poll flag request: %v", err)
+		return false, fmt.Errorf("creating update poll flag request: %v", err)
 	}
 
+	c.mu.RLock()
+	if c.etag != "" {
+		r.Header.Set("If-None-Match", c.etag)
+	}
+	c.mu.RUnlock()
+
 	resp, err := c.opts.httpClient.Do(r)
 	if err != nil {
-		return fmt.Errorf("getting update poll flag response: %v", err)
+		return false, fmt.Errorf("getting update poll flag response: %v", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotModified {
+		return false, nil
+	}
+
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("invalid status code for %s: %d (%s)", cleanupURL(r.URL), resp.StatusCode, resp.Status)
+		return false, fmt.Errorf("invalid status code for %s: %d (%s)", cleanupURL(r.URL), resp.StatusCode, resp.Status)
+	}
+
+	if err := c.updateStore(resp.Body, PollSource); err != nil {
+		return false, err
 	}
+	c.setETag(resp.Header.Get("ETag"))
 
-	return c.updateStore(resp.Body)
+	return true, nil
+}
+
+func (c *Client) setETag(etag string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.etag = etag
 }
 
 func (c *Client) processEvent(ev Event) {
@@ -337,59 +710,88 @@ func (c *Client) processEvent(ev Event) {
 		return
 	}
 
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	c.modifyStore(func() {
+		c.applyEvent(ev)
+	})
+}
 
+func (c *Client) applyEvent(ev Event) {
 	switch ev.Type {
 	case SaveEvent:
 		for _, f := range ev.Flags {
-			flags := c.store[f.Name]
-
-			var found bool
-			for i, stored := range flags {
-				if stored.ServiceName == f.ServiceName {
-					flags[i] = f
-					found = true
-					break
-				}
-			}
-
-			if !found {
-				c.store[f.Name] = append(c.store[f.Name], f)
-			}
+			c.setEntry(newEntry(f, EventSource))
 		}
 	case DeleteEvent:
 		for _, f := range ev.Flags {
-			flags := c.store[f.Name]
-			for i, stored := range flags {
-				if stored.ServiceName == f.ServiceName {
-					if len(flags) == 1 {
-						delete(c.store, f.Name)
-					} else {
-						flags = append(flags[:i], flags[i+1:]...)
-					}
-					break
-				}
+			c.removeEntry(f.Name, f.ServiceName)
+		}
+	}
+}
+
+// setEntry stores the entry, replacing the one with the same flag name and
+// service, while the caller holds the store lock
+func (c *Client) setEntry(e entry) {
+	entries := c.store[e.Name]
+	for i, stored := range entries {
+		if stored.ServiceName == e.ServiceName {
+			entries[i] = e
+			return
+		}
+	}
+
+	c.store[e.Name] = append(entries, e)
+}
+
+// removeEntry removes the entry with the given flag name and service, while
+// the caller holds the store lock
+func (c *Client) removeEntry(name, serviceName string) (entry, bool) {
+	entries := c.store[name]
+	for i, stored := range entries {
+		if stored.ServiceName == serviceName {
+			if len(entries) == 1 {
+				delete(c.store, name)
+			} else {
+				c.store[name] = append(entries[:i], entries[i+1:]...)
 			}
+			return stored, true
 		}
 	}
+
+	return entry{}, false
+}
+
+// publish makes a copy of the store available to the readers, while the
+// caller holds the store lock. The entries are copied, as the store ones are
+// modified in place.
+func (c *Client) publish() {
+	store := make(map[string][]entry, len(c.store))
+	for name, entries := range c.store {
+		store[name] = append([]entry(nil), entries...)
+	}
+
+	c.current.Store(store)
 }
 
-func (c *Client) updateStore(r io.Reader) error {
+// loadStore returns the last published store, which must not be modified
+func (c *Client) loadStore() map[string][]entry {
+	return c.current.Load().(map[string][]entry)
+}
+
+func (c *Client) updateStore(r io.Reader, source Source) error {
 	var flags []Flag
 	if err := json.NewDecoder(r).Decode(&flags); err != nil {
 		return fmt.Errorf("decoding flag data: %v", err)
 	}
 
-	store := map[string][]Flag{}
+	store := map[string][]entry{}
 	for _, f := range flags {
 		f = f.Normalized()
-		store[f.Name] = append(store[f.Name], f)
+		store[f.Name] = append(store[f.Name], newEntry(f, source))
 	}
 
-	c.mu.Lock()
-	c.store = store
-	c.mu.Unlock()
+	c.modifyStore(func() {
+		c.store = store
+	})
 
 	return nil
 }
@@ -400,7 +802,9 @@ func normalizeSerivceName(name string) string {
 	return name
 }
 
-func normalizeName(value string) string {
+// NormalizeName returns the flag name as it is stored, lower cased and with
+// any character other than letters and digits replaced by a dot
+func NormalizeName(value string) string {
 	return strings.Map(func(r rune) rune {
 		if !unicode.IsDigit(r) && !unicode.IsLetter(r) {
 			return '.'
@@ -420,13 +824,76 @@ func (f Flag) String() string {
 	return name + "=" + f.RawValue
 }
 
+// Normalized returns a copy of the flag with normalized names, and with the
+// condition values converted to the underlying types of their value types,
+// such as the lists decoded from BSON
 func (f Flag) Normalized() Flag {
-	f.Name = normalizeName(f.Name)
+	f.Name = NormalizeName(f.Name)
 	f.ServiceName = normalizeSerivceName(f.ServiceName)
+	f.Condition = f.Condition.normalized()
+
+	if len(f.Rules) > 0 {
+		rules := make([]Rule, len(f.Rules))
+		for i, r := range f.Rules {
+			r.Condition = r.Condition.normalized()
+			rules[i] = r
+		}
+		f.Rules = rules
+	}
 
 	return f
 }
 
+// Active reports whether the given time is within the flag activation window
+func (f Flag) Active(t time.Time) bool {
+	return (f.ActiveFrom == nil || !t.Before(*f.ActiveFrom)) &&
+		(f.ActiveUntil == nil || t.Before(*f.ActiveUntil))
+}
+
+// Validate checks if the flag condition, rules, variants and activation window
+// are valid
+func (f Flag) Validate() error {
+	if err := f.Condition.Validate(); err != nil {
+		return err
+	}
+
+	if f.ActiveFrom != nil && f.ActiveUntil != nil && !f.ActiveUntil.After(*f.ActiveFrom) {
+		return fmt.Errorf("activation window ends at %s, before it starts at %s",
+			f.ActiveUntil.Format(time.RFC3339), f.ActiveFrom.Format(time.RFC3339))
+	}
+
+	for i, r := range f.Rules {
+		if err := r.Condition.Validate(); err != nil {
+			return fmt.Errorf("invalid rule %d: %v", i, err)
+		}
+	}
+
+	if len(f.Variants) == 0 {
+		return nil
+	}
+
+	var total int
+	names := map[string]bool{}
+	for _, v := range f.Variants {
+		if err := v.Validate(); err != nil {
+			return err
+		}
+
+		if names[v.Name] {
+			return fmt.Errorf("duplicate variant %s", v.Name)
+		}
+		names[v.Name] = true
+
+		total += v.Weight
+	}
+
+	if total == 0 {
+		return errors.New("variant weights sum up to 0")
+	}
+
+	return nil
+}
+
 func cleanupURL(u *url.URL) string {
 	if u.User != nil {
 		u.User = url.User(u.User.Username())
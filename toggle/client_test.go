@@ -3,13 +3,21 @@ package toggle_test
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/globusdigital/feature-toggles/api"
+	"github.com/globusdigital/feature-toggles/messaging"
+	"github.com/globusdigital/feature-toggles/storage"
 	"github.com/globusdigital/feature-toggles/toggle"
 	gomock "github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
@@ -130,6 +138,266 @@ func TestClient_Get(t *testing.T) {
 	}
 }
 
+// benchmarkClient returns a client with a plain, a conditional and a rules
+// flag, matched by its condition values, and two conditional flags which
+// aren't matched. The < and > operators of int values compare the literal
+// with the value.
+func benchmarkClient(tb testing.TB) *toggle.Client {
+	cond, err := toggle.ParseCondition(strings.NewReader("tenant == 'beta' && userID > 100"))
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	failing, err := toggle.ParseCondition(strings.NewReader("tenant == 'beta' && userID < 100"))
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	tenants := make([]string, 200)
+	for i := range tenants {
+		tenants[i] = fmt.Sprintf("tenant%d", i)
+	}
+	failingList := toggle.Condition{Fields: []toggle.ConditionField{
+		{ConditionValue: toggle.ConditionValue{Name: "tenant", Type: toggle.StringType, Value: tenants}, Op: toggle.InOp},
+	}}
+
+	c := toggle.New("serv1", toggle.For(
+		toggle.ConditionValue{Name: "tenant", Value: "beta"},
+		toggle.ConditionValue{Name: "userID", Value: int64(42)},
+	))
+	c.ParseEnv(seed1)
+	c.SetFlags(
+		toggle.Flag{Name: "conditional", ServiceName: "serv1", RawValue: "t", Value: true, Condition: cond},
+		toggle.Flag{Name: "rules", ServiceName: "serv1", RawValue: "f", Rules: []toggle.Rule{
+			{Condition: toggle.Condition{Fields: []toggle.ConditionField{{ConditionValue: toggle.ConditionValue{Name: "tenant", Type: toggle.StringType, Value: "alpha"}}}}, RawValue: "alpha"},
+			{Condition: cond, RawValue: "t", Value: true},
+		}},
+		toggle.Flag{Name: "failing", ServiceName: "serv1", RawValue: "t", Value: true, Condition: failing},
+		toggle.Flag{Name: "failing.list", ServiceName: "serv1", RawValue: "t", Value: true, Condition: failingList},
+	)
+
+	return c
+}
+
+func TestClient_Get_allocs(t *testing.T) {
+	c := benchmarkClient(t)
+
+	for _, name := range []string{"feature.1", "conditional", "rules", "missing", "failing", "failing.list"} {
+		allocs := testing.AllocsPerRun(100, func() {
+			c.Get(name)
+			c.GetRaw(name)
+		})
+		assert.Zero(t, allocs, name)
+	}
+}
+
+func BenchmarkClient_Get(b *testing.B) {
+	c := benchmarkClient(b)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		c.Get("feature.1")
+	}
+}
+
+func BenchmarkClient_Get_conditional(b *testing.B) {
+	c := benchmarkClient(b)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		c.Get("conditional")
+	}
+}
+
+func BenchmarkClient_Get_rules(b *testing.B) {
+	c := benchmarkClient(b)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		c.Get("rules")
+	}
+}
+
+func BenchmarkClient_Get_failing(b *testing.B) {
+	c := benchmarkClient(b)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		c.Get("failing.list")
+	}
+}
+
+func BenchmarkClient_Get_values(b *testing.B) {
+	c := benchmarkClient(b)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		c.Get("conditional", toggle.ForInt("userID", int64(i)))
+	}
+}
+
+func BenchmarkClient_Get_parallel(b *testing.B) {
+	c := benchmarkClient(b)
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			c.Get("conditional")
+		}
+	})
+}
+
+var typedSeed = []string{
+	"FEATURE_SERV1_WORKERS=12",
+	"FEATURE_SERV1_RATIO=0.25",
+	"FEATURE_SERV1_TIMEOUT=1m30s",
+	"FEATURE_SERV1_LIMITS={\"max\": 10, \"name\": \"strict\"}",
+	"FEATURE_SERV1_BROKEN=many",
+}
+
+type testLogger struct {
+	lines []string
+}
+
+func (l *testLogger) Println(v ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprintln(v...))
+}
+
+func (l *testLogger) Printf(format string, v ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
+
+func TestClient_GetTyped(t *testing.T) {
+	type limits struct {
+		Max  int    `json:"max"`
+		Name string `json:"name"`
+	}
+
+	tests := []struct {
+		name    string
+		get     func(c *toggle.Client) interface{}
+		want    interface{}
+		wantLog bool
+	}{
+		{name: "int", get: func(c *toggle.Client) interface{} { return c.GetInt("workers", 4) }, want: int64(12)},
+		{name: "int missing", get: func(c *toggle.Client) interface{} { return c.GetInt("missing", 4) }, want: int64(4)},
+		{name: "int invalid", get: func(c *toggle.Client) interface{} { return c.GetInt("broken", 4) }, want: int64(4), wantLog: true},
+		{name: "float", get: func(c *toggle.Client) interface{} { return c.GetFloat("ratio", 1) }, want: 0.25},
+		{name: "float invalid", get: func(c *toggle.Client) interface{} { return c.GetFloat("broken", 1) }, want: 1.0, wantLog: true},
+		{name: "duration", get: func(c *toggle.Client) interface{} { return c.GetDuration("timeout", time.Second) }, want: 90 * time.Second},
+		{name: "duration missing", get: func(c *toggle.Client) interface{} { return c.GetDuration("missing", time.Second) }, want: time.Second},
+		{name: "duration invalid", get: func(c *toggle.Client) interface{} { return c.GetDuration("broken", time.Second) }, want: time.Second, wantLog: true},
+		{name: "json", get: func(c *toggle.Client) interface{} {
+			l := limits{Max: 1, Name: "default"}
+			c.GetJSON("limits", &l)
+			return l
+		}, want: limits{Max: 10, Name: "strict"}},
+		{name: "json missing", get: func(c *toggle.Client) interface{} {
+			l := limits{Max: 1, Name: "default"}
+			c.GetJSON("missing", &l)
+			return l
+		}, want: limits{Max: 1, Name: "default"}},
+		{name: "json invalid", get: func(c *toggle.Client) interface{} {
+			l := limits{Max: 1, Name: "default"}
+			c.GetJSON("broken", &l)
+			return l
+		}, want: limits{Max: 1, Name: "default"}, wantLog: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &testLogger{}
+			c := toggle.New("serv1", toggle.WithLogger(l))
+			c.ParseEnv(typedSeed)
+
+			a := assert.New(t)
+			a.Equal(tt.want, tt.get(c))
+			a.Equal(tt.wantLog, len(l.lines) > 0, l.lines)
+		})
+	}
+}
+
+func TestClient_Get_semver(t *testing.T) {
+	a := assert.New(t)
+
+	c := toggle.New("serv1")
+	c.SetFlags(toggle.Flag{Name: "new.checkout", ServiceName: "serv1", RawValue: "t", Value: true, Condition: toggle.Condition{Fields: []toggle.ConditionField{
+		{ConditionValue: toggle.ConditionValue{Name: "appVersion", Type: toggle.SemverType, Value: "4.12.0"}, Op: toggle.GeOp},
+	}}})
+
+	a.False(c.Get("new.checkout", toggle.ForSemver("appVersion", "4.9.3")))
+	a.False(c.Get("new.checkout", toggle.ForSemver("appVersion", "4.12.0-rc.1")))
+	a.True(c.Get("new.checkout", toggle.ForSemver("appVersion", "4.12.0")))
+	a.True(c.Get("new.checkout", toggle.ForSemver("appVersion", "v10.0.0")))
+	a.False(c.Get("new.checkout", toggle.ForString("appVersion", "5.0.0")))
+}
+
+func TestClient_Get_time(t *testing.T) {
+	a := assert.New(t)
+
+	now := time.Date(2021, 3, 1, 8, 0, 0, 0, time.UTC)
+	from, until := time.Date(2021, 3, 1, 9, 0, 0, 0, time.UTC), time.Date(2021, 3, 3, 0, 0, 0, 0, time.UTC)
+
+	c := toggle.New("serv1", toggle.WithClock(func() time.Time { return now }))
+	c.SetFlags(
+		toggle.Flag{Name: "sale", ServiceName: "serv1", RawValue: "t", Value: true, ActiveFrom: &from, ActiveUntil: &until},
+		toggle.Flag{Name: "banner", ServiceName: "serv1", RawValue: "t", Value: true, Condition: toggle.Condition{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Name: toggle.NowValue, Type: toggle.TimeType, Value: time.Date(2021, 3, 2, 0, 0, 0, 0, time.UTC)}, Op: toggle.LtOp},
+		}}},
+	)
+
+	a.False(c.Get("sale"))
+	a.Equal(toggle.InactiveReason, c.Evaluate("sale").Reason)
+	a.True(c.Get("banner"))
+
+	now = from
+	a.True(c.Get("sale"))
+	a.True(c.Get("banner"))
+
+	now = time.Date(2021, 3, 2, 12, 0, 0, 0, time.UTC)
+	a.True(c.Get("sale"))
+	a.False(c.Get("banner"))
+	a.False(c.Get("banner", toggle.ForTime(toggle.NowValue, time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC))), "call values don't replace the clock")
+	a.True(c.Get("sale", toggle.ForTime(toggle.NowValue, time.Date(2021, 3, 1, 8, 0, 0, 0, time.UTC))))
+	ctx := toggle.WithValues(context.Background(), toggle.ConditionValue{Name: toggle.NowValue, Type: toggle.TimeType, Value: from.Add(-time.Hour)})
+	a.True(c.GetCtx(ctx, "sale"), "context values don't replace the clock")
+
+	now = until
+	a.False(c.Get("sale"))
+}
+
+func TestClient_Override(t *testing.T) {
+	a := assert.New(t)
+
+	var evaluated []string
+	c := toggle.New("serv1", toggle.WithEvaluationListener(func(d toggle.EvaluationDetail) {
+		evaluated = append(evaluated, d.Name+"="+d.RawValue)
+	}))
+	c.ParseEnv(seed1)
+
+	restore := c.Override(
+		toggle.NewFlag("Feature_1", "serv1", "f"),
+		toggle.NewFlag("feature.new", "serv1", "t"),
+		toggle.NewFlag("feature.other", "serv2", "t"),
+	)
+	a.False(c.Get("feature.1"))
+	a.True(c.Get("feature.new"))
+	a.Equal(toggle.ManualSource, c.Evaluate("feature.1").Source)
+
+	restore()
+	a.True(c.Get("feature.1"))
+	a.False(c.Get("feature.new"))
+	a.Equal(toggle.EnvSource, c.Evaluate("feature.1").Source)
+
+	c.SetFlags(toggle.NewFlag("feature.new", "", "t"))
+	a.True(c.Get("feature.new", toggle.Global))
+
+	a.Equal([]string{
+		"feature.1=f", "feature.new=t", "feature.1=f",
+		"feature.1=t", "feature.new=", "feature.1=t",
+		"feature.new=t",
+	}, evaluated)
+}
+
 func TestClient_Connect(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -285,6 +553,318 @@ func TestClient_Connect(t *testing.T) {
 	}
 }
 
+func TestClient_Connect_backoff(t *testing.T) {
+	a := assert.New(t)
+
+	var mu sync.Mutex
+	var seeds, polls int
+	pollFailures := 3
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if strings.HasSuffix(r.URL.Path, "/initial") {
+			seeds++
+			if seeds < 3 {
+				http.Error(w, "error", 500)
+				return
+			}
+		} else {
+			polls++
+			if polls <= pollFailures {
+				http.Error(w, "error", 500)
+				return
+			}
+		}
+
+		b, err := json.Marshal(initialData)
+		a.NoError(err)
+		_, _ = w.Write(b)
+	}))
+	defer ts.Close()
+
+	seed := append(seed1, "FEATURE__GLOBAL__"+toggle.ServerAddressFlag+"="+ts.URL)
+	backoff := toggle.Backoff{Initial: 10 * time.Millisecond, Multiplier: 2}
+
+	// Poll failures are retried without waiting for the polling interval
+	c := toggle.New("serv1", toggle.WithBackoff(backoff), toggle.WithPollingUpdateDuration(200*time.Millisecond))
+	c.ParseEnv(seed)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 400*time.Millisecond)
+	defer cancel()
+
+	var errs int
+	for range c.Connect(ctx) {
+		errs++
+	}
+
+	mu.Lock()
+	a.Equal(3, seeds)
+	a.Equal(5, errs)
+	a.Equal(4, polls, "the failed polls are retried before the next interval")
+	a.Equal("0", c.GetRaw("feature.2"))
+
+	// The client gives up seeding after the maximum retries
+	seeds, pollFailures = -10, 0
+	mu.Unlock()
+
+	backoff.MaxRetries = 2
+	c = toggle.New("serv1", toggle.WithBackoff(backoff))
+	c.ParseEnv(seed)
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var last error
+	for err := range c.Connect(ctx) {
+		last = err
+	}
+	a.NoError(ctx.Err(), "errC closed after giving up")
+	a.Contains(last.Error(), "giving up after 2 retries")
+	mu.Lock()
+	a.Equal(-7, seeds)
+	mu.Unlock()
+}
+
+func TestClient_ConditionalPoll(t *testing.T) {
+	a := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "toggle-snapshot")
+	a.NoError(err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "flags.json")
+
+	var mu sync.Mutex
+	var polls, notModified int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		w.Header().Set("ETag", `"1"`)
+		if !strings.HasSuffix(r.URL.Path, "/initial") {
+			polls++
+			if r.Header.Get("If-None-Match") == `"1"` {
+				if notModified == 0 {
+					// Unmodified polls don't save the snapshot again
+					a.NoError(os.Remove(file))
+				}
+				notModified++
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+
+		b, err := json.Marshal(initialData)
+		a.NoError(err)
+		_, _ = w.Write(b)
+	}))
+	defer ts.Close()
+
+	c := toggle.New("serv1", toggle.WithPollingUpdateDuration(50*time.Millisecond), toggle.WithSnapshotFile(file))
+	c.ParseEnv(append(seed1, "FEATURE__GLOBAL__"+toggle.ServerAddressFlag+"="+ts.URL))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	for range c.Connect(ctx) {
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	a.True(polls > 0)
+	a.Equal(polls, notModified)
+	a.Equal("0", c.GetRaw("feature.2"))
+
+	_, err = os.Stat(file)
+	a.True(os.IsNotExist(err), "snapshot saved after unmodified polls")
+}
+
+func TestClient_ConditionalPoll_setFlags(t *testing.T) {
+	a := assert.New(t)
+
+	c := toggle.New("serv1", toggle.WithPollingUpdateDuration(50*time.Millisecond))
+
+	var mu sync.Mutex
+	var polls int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		w.Header().Set("ETag", `"1"`)
+		if r.Header.Get("If-None-Match") == `"1"` {
+			if polls++; polls == 1 {
+				c.SetFlags(toggle.NewFlag("feature.2", "serv1", "manual"))
+			}
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		b, err := json.Marshal(initialData)
+		a.NoError(err)
+		_, _ = w.Write(b)
+	}))
+	defer ts.Close()
+
+	c.ParseEnv(append(seed1, "FEATURE__GLOBAL__"+toggle.ServerAddressFlag+"="+ts.URL))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	for range c.Connect(ctx) {
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	a.True(polls > 0)
+	a.Equal("0", c.GetRaw("feature.2"), "manual flags are replaced by the next poll")
+}
+
+func TestClient_ServerEvents(t *testing.T) {
+	a := assert.New(t)
+
+	ts := httptest.NewServer(api.Handler("/flags", storage.NewMem(), messaging.NewNoop()))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "toggle-snapshot")
+	a.NoError(err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "flags.json")
+
+	c := toggle.New("serv1", toggle.WithServerEvents(), toggle.WithSnapshotFile(file))
+	c.ParseEnv(append(seed1, "FEATURE__GLOBAL__"+toggle.ServerAddressFlag+"="+ts.URL))
+
+	changed := make(chan string, 1)
+	c.OnChange("feature.2", func(old, new toggle.Flag) {
+		changed <- new.RawValue
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	errC := c.Connect(ctx)
+	go func() {
+		for range errC {
+		}
+	}()
+
+	// Wait for the stream to be established before saving
+	time.Sleep(200 * time.Millisecond)
+	c.SetFlags(toggle.NewFlag("manual", "serv1", "t"))
+
+	resp, err := http.Post(ts.URL+"/flags/serv1", "application/json", strings.NewReader(`[{"name":"feature.2","service":"serv1","raw":"t","value":true}]`))
+	a.NoError(err)
+	resp.Body.Close()
+	a.Equal(http.StatusNoContent, resp.StatusCode)
+
+	select {
+	case <-ctx.Done():
+		t.Fatal("Expected a flag change")
+	case raw := <-changed:
+		a.Equal("t", raw)
+	}
+	a.True(c.Get("feature.2"))
+	a.Equal(toggle.EventSource, c.Evaluate("feature.2").Source)
+
+	var snapshot struct {
+		Flags []toggle.Flag `json:"flags"`
+	}
+	saved := toggle.NewFlag("feature.2", "serv1", "t")
+	a.Eventually(func() bool {
+		b, err := ioutil.ReadFile(file)
+		if err != nil || json.Unmarshal(b, &snapshot) != nil {
+			return false
+		}
+		for _, f := range snapshot.Flags {
+			if reflect.DeepEqual(f, saved) {
+				return true
+			}
+		}
+		return false
+	}, time.Second, 10*time.Millisecond, "the event flags are saved")
+	for _, f := range snapshot.Flags {
+		a.NotEqual("manual", f.Name, "manual flags aren't saved")
+	}
+}
+
+func TestClient_Snapshot(t *testing.T) {
+	a := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "toggle-snapshot")
+	a.NoError(err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "flags.json")
+
+	var serverErr bool
+	var mu sync.Mutex
+	var seeded []toggle.Flag
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if serverErr {
+			http.Error(w, "error", 500)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/initial") {
+			mu.Lock()
+			a.NoError(json.NewDecoder(r.Body).Decode(&seeded))
+			mu.Unlock()
+		}
+		b, err := json.Marshal(initialData)
+		a.NoError(err)
+		_, _ = w.Write(b)
+	}))
+	defer ts.Close()
+
+	seed := append(seed1, "FEATURE__GLOBAL__"+toggle.ServerAddressFlag+"="+ts.URL)
+
+	connect := func(opts ...toggle.ClientOption) *toggle.Client {
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+
+		c := toggle.New("serv1", append(opts, toggle.WithSnapshotFile(file))...)
+		c.ParseEnv(seed)
+		errC := c.Connect(ctx)
+		go func() {
+			for range errC {
+			}
+		}()
+
+		<-ctx.Done()
+
+		return c
+	}
+
+	c := connect()
+	a.Equal("0", c.GetRaw("feature.2"))
+	a.FileExists(file)
+
+	serverErr = true
+
+	c = connect()
+	a.Equal("0", c.GetRaw("feature.2"))
+	a.Equal("no", c.GetRaw("feature.3"), "snapshot flags override env flags")
+	a.Equal("1", c.GetRaw("feature.4"), "env flags missing in the snapshot are kept")
+	a.Equal(toggle.SnapshotSource, c.Evaluate("feature.2").Source)
+	a.NoError(c.WaitReady(context.Background()), "the snapshot makes the client ready")
+
+	serverErr = false
+
+	c = connect()
+	mu.Lock()
+	a.Contains(seeded, toggle.NewFlag("feature.3", "serv1", "yes"), "env flags in the snapshot are seeded")
+	mu.Unlock()
+
+	serverErr = true
+
+	c = connect(toggle.WithSnapshotMaxAge(time.Nanosecond))
+	a.Equal("f", c.GetRaw("feature.2"))
+	a.Equal(toggle.EnvSource, c.Evaluate("feature.2").Source)
+	a.Equal(toggle.ErrStopped, c.WaitReady(context.Background()))
+}
+
 func canceledCtx(d time.Duration) func() context.Context {
 	return func() context.Context {
 		ctx, cancel := context.WithCancel(context.Background())
@@ -335,6 +915,8 @@ func TestClient_MarshalJSON(t *testing.T) {
 }
 
 func TestFlag_UnmarshalJSON(t *testing.T) {
+	activeUntil := time.Date(2021, 3, 3, 0, 0, 0, 0, time.UTC)
+
 	tests := []struct {
 		name    string
 		d       []byte
@@ -358,6 +940,30 @@ func TestFlag_UnmarshalJSON(t *testing.T) {
 		{name: "expr 2", d: []byte(`
 {"name":"f1","service":"s1","raw":"1","value":true,"expr":"n1 != true || n2 == true"}
 		`), flag: toggle.Flag{Name: "f1", ServiceName: "s1", RawValue: "1", Value: true, Condition: toggle.Condition{Op: toggle.OrOp, Fields: []toggle.ConditionField{{Op: toggle.NeOp, ConditionValue: toggle.ConditionValue{Name: "n1", Type: toggle.BoolType, Value: true}}, {Op: toggle.EqOp, ConditionValue: toggle.ConditionValue{Name: "n2", Type: toggle.BoolType, Value: true}}}}, Expr: "n1 != true || n2 == true"}},
+		{name: "bucket", d: []byte(`
+{"name":"f1","service":"s1","raw":"1","value":true,"cond":{"fields":[{"op":2,"name":"userID","type":0,"value":25,"bucket":{"seed":"f1"}}]}}
+		`), flag: toggle.Flag{Name: "f1", ServiceName: "s1", RawValue: "1", Value: true, Condition: toggle.Condition{Fields: []toggle.ConditionField{{Op: toggle.LtOp, ConditionValue: toggle.ConditionValue{Name: "userID", Type: toggle.IntType, Value: int64(25)}, Bucket: &toggle.Bucket{Seed: "f1"}}}}}},
+		{name: "bucket expr", d: []byte(`
+{"name":"f1","service":"s1","raw":"1","value":true,"expr":"bucket(userID, 'f1') < 25"}
+		`), flag: toggle.Flag{Name: "f1", ServiceName: "s1", RawValue: "1", Value: true, Condition: toggle.Condition{Fields: []toggle.ConditionField{{Op: toggle.LtOp, ConditionValue: toggle.ConditionValue{Name: "userID", Type: toggle.IntType, Value: int64(25)}, Bucket: &toggle.Bucket{Seed: "f1"}}}}, Expr: "bucket(userID, 'f1') < 25"}},
+		{name: "in", d: []byte(`
+{"name":"f1","service":"s1","raw":"1","value":true,"cond":{"fields":[{"op":4,"name":"userID","type":0,"value":[1,2]},{"op":5,"name":"tenant","type":3,"value":["a"]}]}}
+		`), flag: toggle.Flag{Name: "f1", ServiceName: "s1", RawValue: "1", Value: true, Condition: toggle.Condition{Fields: []toggle.ConditionField{{Op: toggle.InOp, ConditionValue: toggle.ConditionValue{Name: "userID", Type: toggle.IntType, Value: []int64{1, 2}}}, {Op: toggle.NotInOp, ConditionValue: toggle.ConditionValue{Name: "tenant", Type: toggle.StringType, Value: []string{"a"}}}}}}},
+		{name: "in expr", d: []byte(`
+{"name":"f1","service":"s1","raw":"1","value":true,"expr":"tenant in ['a', 'b']"}
+		`), flag: toggle.Flag{Name: "f1", ServiceName: "s1", RawValue: "1", Value: true, Condition: toggle.Condition{Fields: []toggle.ConditionField{{Op: toggle.InOp, ConditionValue: toggle.ConditionValue{Name: "tenant", Type: toggle.StringType, Value: []string{"a", "b"}}}}}, Expr: "tenant in ['a', 'b']"}},
+		{name: "not", d: []byte(`
+{"name":"f1","service":"s1","raw":"1","value":true,"cond":{"conds":[{"not":true,"fields":[{"op":7,"name":"version","type":0,"value":3}]}]}}
+		`), flag: toggle.Flag{Name: "f1", ServiceName: "s1", RawValue: "1", Value: true, Condition: toggle.Condition{Conditions: []toggle.Condition{{Not: true, Fields: []toggle.ConditionField{{Op: toggle.GeOp, ConditionValue: toggle.ConditionValue{Name: "version", Type: toggle.IntType, Value: int64(3)}}}}}}}},
+		{name: "not expr", d: []byte(`
+{"name":"f1","service":"s1","raw":"1","value":true,"expr":"not (version >= 3)"}
+		`), flag: toggle.Flag{Name: "f1", ServiceName: "s1", RawValue: "1", Value: true, Condition: toggle.Condition{Conditions: []toggle.Condition{{Not: true, Fields: []toggle.ConditionField{{Op: toggle.GeOp, ConditionValue: toggle.ConditionValue{Name: "version", Type: toggle.IntType, Value: int64(3)}}}}}}, Expr: "not (version >= 3)"}},
+		{name: "time", d: []byte(`
+{"name":"f1","service":"s1","raw":"1","value":true,"activeUntil":"2021-03-03T00:00:00Z","cond":{"fields":[{"op":7,"name":"now","type":5,"value":"2021-03-01T09:00:00Z"}]}}
+		`), flag: toggle.Flag{Name: "f1", ServiceName: "s1", RawValue: "1", Value: true, ActiveUntil: &activeUntil, Condition: toggle.Condition{Fields: []toggle.ConditionField{{Op: toggle.GeOp, ConditionValue: toggle.ConditionValue{Name: "now", Type: toggle.TimeType, Value: time.Date(2021, 3, 1, 9, 0, 0, 0, time.UTC)}}}}}},
+		{name: "time expr", d: []byte(`
+{"name":"f1","service":"s1","raw":"1","value":true,"expr":"now >= 2021-03-01T09:00:00Z"}
+		`), flag: toggle.Flag{Name: "f1", ServiceName: "s1", RawValue: "1", Value: true, Condition: toggle.Condition{Fields: []toggle.ConditionField{{Op: toggle.GeOp, ConditionValue: toggle.ConditionValue{Name: "now", Type: toggle.TimeType, Value: time.Date(2021, 3, 1, 9, 0, 0, 0, time.UTC)}}}}, Expr: "now >= 2021-03-01T09:00:00Z"}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -3,7 +3,12 @@ package toggle
 import (
 	"encoding/json"
 	"fmt"
+	"hash/fnv"
+	"reflect"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type ValueType int
@@ -15,6 +20,12 @@ const (
 	FloatType                   // float
 	BoolType                    // bool
 	StringType                  // string
+	// SemverType values are semantic version strings, such as 1.2.3-beta.1,
+	// ordered by their precedence
+	SemverType // semver
+	// TimeType values are time.Time instants, written as RFC 3339 timestamps
+	// such as 2021-03-01T09:00:00Z, or as dates at midnight UTC
+	TimeType // time
 )
 
 const (
@@ -25,14 +36,27 @@ const (
 )
 
 const (
-	EqOp FieldOp = iota // =
-	NeOp                // !=
-	LtOp                // <
-	GtOp                // >
+	EqOp         FieldOp = iota // =
+	NeOp                        // !=
+	LtOp                        // <
+	GtOp                        // >
+	InOp                        // in
+	NotInOp                     // not in
+	LeOp                        // <=
+	GeOp                        // >=
+	MatchesOp                   // matches
+	LikeOp                      // like
+	StartsWithOp                // startsWith
+	EndsWithOp                  // endsWith
+	ContainsOp                  // contains
 
 	invalidFieldOp // err
 
 	ServiceNameValue = "serviceName"
+	// NowValue is the name of the current time value of the client clock,
+	// which is available to the conditions of any flag. Context and call values
+	// with the same name are ignored.
+	NowValue = "now"
 )
 
 type ConditionValue struct {
@@ -41,19 +65,45 @@ type ConditionValue struct {
 	Value interface{} `json:"value"`
 }
 
+// ConditionField compares the named condition value with the field value.
+// The < and > operators of plain int, float and string fields compare the
+// field value with the named value instead, so that a field with LtOp and the
+// value 10 matches the named values above 10. The field value of the in and not in operators is a list, a slice of the
+// underlying type of the field type, such as []string for StringType. The
+// matches and like operators compare string values with an RE2 regular
+// expression and a glob pattern, where * matches any text and ? any single
+// character.
 type ConditionField struct {
 	ConditionValue
 	Op FieldOp `json:"op,omitempty"`
+
+	// Bucket, when set, compares the rollout bucket of the named value
+	// instead of the value itself
+	Bucket *Bucket `json:"bucket,omitempty"`
+
+	// re is the compiled pattern of a matches or like field, which is set
+	// when the field is decoded or parsed
+	re *regexp.Regexp
+}
+
+// Bucket describes a percentage rollout. The named condition value is hashed
+// together with the seed into a stable bucket in the range [0, 100), so that
+// the same value always lands in the same bucket, regardless of the process or
+// service performing the evaluation.
+type Bucket struct {
+	Seed string `json:"seed,omitempty"`
 }
 
+// BucketCount is the number of rollout buckets
+const BucketCount = 100
+
 type Condition struct {
 	Op         ConditionOp      `json:"op,omitempty"`
 	Conditions []Condition      `json:"conds,omitempty"`
 	Fields     []ConditionField `json:"fields,omitempty"`
-}
 
-type matcher interface {
-	match(values []ConditionValue) bool
+	// Not negates the result of the condition
+	Not bool `json:"not,omitempty"`
 }
 
 func (v *ConditionField) UnmarshalJSON(b []byte) error {
@@ -63,20 +113,231 @@ func (v *ConditionField) UnmarshalJSON(b []byte) error {
 		return err
 	}
 
-	if val.Type == IntType {
-		if f, ok := val.Value.(float64); ok {
-			val.Value = int64(f)
+	val.Value = decodedValue(val.Type, val.Value)
+
+	*v = ConditionField(val).withPattern()
+
+	return nil
+}
+
+// decodedValue converts a value decoded from JSON or BSON to the underlying
+// type expected by the value type. Lists are converted to slices of it.
+func decodedValue(t ValueType, v interface{}) interface{} {
+	switch val := v.(type) {
+	case float64:
+		if t == IntType {
+			return int64(val)
+		}
+	case int32:
+		if t == IntType {
+			return int64(val)
+		}
+	case string:
+		if t == TimeType {
+			if tm, err := parseTime(val); err == nil {
+				return tm
+			}
+		}
+	case nil, int64, bool, time.Time, []int64, []float64, []bool, []string:
+	default:
+		rv := reflect.ValueOf(v)
+
+		// BSON datetimes are decoded as a named int64 type of milliseconds
+		if t == TimeType && rv.Kind() == reflect.Int64 {
+			return time.Unix(0, rv.Int()*int64(time.Millisecond)).UTC()
+		}
+
+		// BSON arrays are decoded as a named []interface{} type
+		if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Interface {
+			elems := make([]interface{}, rv.Len())
+			for i := range elems {
+				elems[i] = decodedValue(t, rv.Index(i).Interface())
+			}
+			return typedList(t, elems)
 		}
 	}
 
-	*v = ConditionField(val)
+	return v
+}
 
-	return nil
+// typedList converts the list elements to a slice of the underlying type
+// of the value type. The elements are returned as is if any of them has a
+// different type.
+func typedList(t ValueType, elems []interface{}) interface{} {
+	var ok bool
+	switch t {
+	case IntType:
+		list := make([]int64, len(elems))
+		for i, e := range elems {
+			if list[i], ok = e.(int64); !ok {
+				return elems
+			}
+		}
+		return list
+	case FloatType:
+		list := make([]float64, len(elems))
+		for i, e := range elems {
+			if list[i], ok = e.(float64); !ok {
+				return elems
+			}
+		}
+		return list
+	case BoolType:
+		list := make([]bool, len(elems))
+		for i, e := range elems {
+			if list[i], ok = e.(bool); !ok {
+				return elems
+			}
+		}
+		return list
+	case StringType, SemverType:
+		list := make([]string, len(elems))
+		for i, e := range elems {
+			if list[i], ok = e.(string); !ok {
+				return elems
+			}
+		}
+		return list
+	}
+
+	return elems
+}
+
+// parseTime parses an RFC 3339 timestamp, or a date at midnight UTC
+func parseTime(s string) (time.Time, error) {
+	if len(s) == len(dateLayout) {
+		return time.Parse(dateLayout, s)
+	}
+
+	return time.Parse(time.RFC3339Nano, s)
+}
+
+const dateLayout = "2006-01-02"
+
+// list returns the elements of a list field value, or an error if it isn't a
+// slice of the underlying type of the field type
+func (f ConditionField) list() ([]interface{}, error) {
+	var elems []interface{}
+	var ok bool
+
+	switch f.Type {
+	case IntType:
+		var list []int64
+		if list, ok = f.Value.([]int64); ok {
+			for _, e := range list {
+				elems = append(elems, e)
+			}
+		}
+	case FloatType:
+		var list []float64
+		if list, ok = f.Value.([]float64); ok {
+			for _, e := range list {
+				elems = append(elems, e)
+			}
+		}
+	case BoolType:
+		var list []bool
+		if list, ok = f.Value.([]bool); ok {
+			for _, e := range list {
+				elems = append(elems, e)
+			}
+		}
+	case StringType, SemverType:
+		var list []string
+		if list, ok = f.Value.([]string); ok {
+			for _, e := range list {
+				elems = append(elems, e)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("invalid type %v", f.Type)
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("invalid %s list type for value %T", f.Type, f.Value)
+	}
+
+	return elems, nil
 }
 
 // String returns a human-readable representation of a condition field
 func (f ConditionField) String() string {
-	return fmt.Sprintf("%s %s %s(%v)", f.Name, f.Op, f.Type, f.Value)
+	name := f.Name
+	if f.Bucket != nil {
+		name = fmt.Sprintf("bucket(%s, %q)", f.Name, f.Bucket.Seed)
+	}
+	return fmt.Sprintf("%s %s %s(%v)", name, f.Op, f.Type, f.Value)
+}
+
+// Validate checks if the field value is valid
+func (f ConditionField) Validate() error {
+	if f.Op.list() {
+		if _, err := f.list(); err != nil {
+			return err
+		}
+	} else if err := f.ConditionValue.Validate(); err != nil {
+		return err
+	}
+
+	if f.Bucket != nil && f.Type != IntType {
+		return fmt.Errorf("invalid bucket comparison type %v", f.Type)
+	}
+
+	if f.Op.textual() && f.Type != StringType {
+		return fmt.Errorf("invalid %s comparison type %v", f.Op, f.Type)
+	}
+
+	if f.Op == MatchesOp || f.Op == LikeOp {
+		if _, err := f.pattern(); err != nil {
+			return fmt.Errorf("invalid %s pattern %q: %v", f.Op, f.Value, err)
+		}
+	}
+
+	return nil
+}
+
+// list reports whether the operator compares a value with a list
+func (op FieldOp) list() bool {
+	return op == InOp || op == NotInOp
+}
+
+// textual reports whether the operator compares strings as text
+func (op FieldOp) textual() bool {
+	switch op {
+	case MatchesOp, LikeOp, StartsWithOp, EndsWithOp, ContainsOp:
+		return true
+	}
+	return false
+}
+
+// Of returns the rollout bucket of the given condition value
+func (b Bucket) Of(v ConditionValue) int64 {
+	return int64(hashBucket(b.Seed, v.raw(), BucketCount))
+}
+
+// raw returns the string representation of the underlying value
+func (v ConditionValue) raw() string {
+	switch val := v.Value.(type) {
+	case string:
+		return val
+	case int64:
+		return strconv.FormatInt(val, 10)
+	case time.Time:
+		return val.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// hashBucket deterministically distributes the seeded raw value into one of n
+// buckets
+func hashBucket(seed, raw string, n uint64) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(seed))
+	_, _ = h.Write([]byte{':'})
+	_, _ = h.Write([]byte(raw))
+
+	return h.Sum64() % n
 }
 
 // Validate checks if the value type and its underlying type are consistent
@@ -98,6 +359,18 @@ func (v ConditionValue) Validate() error {
 		if _, ok := v.Value.(string); !ok {
 			return fmt.Errorf("invalid string type for value %T", v.Value)
 		}
+	case SemverType:
+		s, ok := v.Value.(string)
+		if !ok {
+			return fmt.Errorf("invalid semver type for value %T", v.Value)
+		}
+		if _, ok := parseSemver(s); !ok {
+			return fmt.Errorf("invalid semantic version %q", s)
+		}
+	case TimeType:
+		if _, ok := v.Value.(time.Time); !ok {
+			return fmt.Errorf("invalid time type for value %T", v.Value)
+		}
 	default:
 		return fmt.Errorf("invalid type %v", v.Type)
 
@@ -122,7 +395,9 @@ func (c Condition) Validate() error {
 	return nil
 }
 
-// Match checks if the given condition values match the condition logic
+// Match checks if the given condition values match the condition logic. The
+// patterns of conditions decoded from JSON or BSON, or parsed from an
+// expression, are only compiled once.
 func (c Condition) Match(values []ConditionValue) bool {
 	return c.match(values)
 }
@@ -139,6 +414,9 @@ func (c Condition) String() string {
 		stringers = append(stringers, m)
 	}
 
+	if c.Not {
+		b.WriteRune('!')
+	}
 	b.WriteRune('(')
 
 	for i, s := range stringers {
@@ -156,102 +434,476 @@ func (c Condition) String() string {
 	return b.String()
 }
 
+// normalized returns a copy of the condition with the field values converted
+// to the underlying types of their value types
+func (c Condition) normalized() Condition {
+	if len(c.Conditions) > 0 {
+		conds := make([]Condition, len(c.Conditions))
+		for i, cond := range c.Conditions {
+			conds[i] = cond.normalized()
+		}
+		c.Conditions = conds
+	}
+
+	if len(c.Fields) > 0 {
+		fields := make([]ConditionField, len(c.Fields))
+		for i, f := range c.Fields {
+			f.Value = decodedValue(f.Type, f.Value)
+			fields[i] = f.withPattern()
+		}
+		c.Fields = fields
+	}
+
+	return c
+}
+
+// withPatterns returns a copy of the condition with the compiled patterns of
+// its matches and like fields
+func (c Condition) withPatterns() Condition {
+	if len(c.Conditions) > 0 {
+		conds := make([]Condition, len(c.Conditions))
+		for i, cond := range c.Conditions {
+			conds[i] = cond.withPatterns()
+		}
+		c.Conditions = conds
+	}
+
+	if len(c.Fields) > 0 {
+		fields := make([]ConditionField, len(c.Fields))
+		for i, f := range c.Fields {
+			fields[i] = f.withPattern()
+		}
+		c.Fields = fields
+	}
+
+	return c
+}
+
+// uses reports whether any of the condition fields compares the named value
+func (c Condition) uses(name string) bool {
+	for _, cond := range c.Conditions {
+		if cond.uses(name) {
+			return true
+		}
+	}
+
+	for _, f := range c.Fields {
+		if f.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (c Condition) hasMatchers() bool {
 	return len(c.Conditions) > 0 || len(c.Fields) > 0
 }
 
 func (c Condition) match(values []ConditionValue) bool {
+	return c.compile()(values)
+}
+
+// matchFunc reports whether the condition values match a compiled condition
+type matchFunc func(values []ConditionValue) bool
+
+func matchAll([]ConditionValue) bool {
+	return true
+}
+
+// compile returns a function equivalent to match, with the condition tree and
+// the field comparisons resolved once instead of on every evaluation. A
+// condition without any matchers matches everything, and one with matchers
+// never matches empty values, unless negated.
+func (c Condition) compile() matchFunc {
 	if !c.hasMatchers() {
-		return true
+		return matchAll
 	}
 
-	matchers := make([]matcher, 0, len(c.Conditions)+len(c.Fields))
-	for _, m := range c.Conditions {
-		matchers = append(matchers, m)
+	matchers := make([]matchFunc, 0, len(c.Conditions)+len(c.Fields))
+	for _, cond := range c.Conditions {
+		matchers = append(matchers, cond.compile())
 	}
-	for _, m := range c.Fields {
-		matchers = append(matchers, m)
+	for _, f := range c.Fields {
+		matchers = append(matchers, f.compile())
 	}
 
-	if len(values) == 0 {
-		return false
+	var match matchFunc
+	if c.Op == OrOp {
+		match = func(values []ConditionValue) bool {
+			if len(values) == 0 {
+				return false
+			}
+			for _, m := range matchers {
+				if m(values) {
+					return true
+				}
+			}
+			return false
+		}
+	} else {
+		match = func(values []ConditionValue) bool {
+			if len(values) == 0 {
+				return false
+			}
+			for _, m := range matchers {
+				if !m(values) {
+					return false
+				}
+			}
+			return true
+		}
 	}
 
-	var match bool
-	for i, m := range matchers {
-		res := m.match(values)
+	if c.Not {
+		return func(values []ConditionValue) bool {
+			return !match(values)
+		}
+	}
 
-		switch c.Op {
-		case OrOp:
-			match = match || res
-		default:
-			if i == 0 {
-				match = true
+	return match
+}
+
+// explain checks if the given condition values match the condition logic, and
+// returns the field responsible for a mismatch. No field is responsible for
+// the mismatch of a negated condition.
+func (c Condition) explain(values []ConditionValue) (bool, *ConditionField) {
+	return c.compileExplain()(values)
+}
+
+// explainFunc is like matchFunc, and also returns the condition field
+// responsible for a mismatch
+type explainFunc func(values []ConditionValue) (bool, *ConditionField)
+
+func explainAll([]ConditionValue) (bool, *ConditionField) {
+	return true, nil
+}
+
+// compileExplain returns a function equivalent to explain, compiled like
+// compile. The returned fields are shared by all of its calls.
+func (c Condition) compileExplain() explainFunc {
+	if !c.hasMatchers() {
+		return explainAll
+	}
+
+	if c.Not {
+		c.Not = false
+		match := c.compile()
+		return func(values []ConditionValue) (bool, *ConditionField) {
+			return !match(values), nil
+		}
+	}
+
+	conds := make([]explainFunc, len(c.Conditions))
+	for i, cond := range c.Conditions {
+		conds[i] = cond.compileExplain()
+	}
+
+	fields := make([]ConditionField, len(c.Fields))
+	matchers := make([]matchFunc, len(c.Fields))
+	for i, f := range c.Fields {
+		fields[i], matchers[i] = f, f.compile()
+	}
+
+	or := c.Op == OrOp
+
+	return func(values []ConditionValue) (bool, *ConditionField) {
+		var failed *ConditionField
+
+		for _, explain := range conds {
+			match, field := explain(values)
+			match = match && len(values) > 0
+			if !match && failed == nil {
+				failed = field
 			}
-			match = match && res
+			if match == or {
+				// The result is settled
+				return match, failed
+			}
+		}
 
-			if !match {
-				break
+		for i, match := range matchers {
+			m := match(values)
+			if !m && failed == nil {
+				failed = &fields[i]
+			}
+			if m == or {
+				return m, failed
 			}
 		}
-	}
 
-	return match
+		return !or, failed
+	}
 }
 
 func (f ConditionField) match(values []ConditionValue) bool {
-	for _, v := range values {
-		if v.Name != f.Name || v.Type != f.Type {
-			continue
+	return f.compile()(values)
+}
+
+// compile returns a function matching the first value with the field name and
+// type. The rollout bucket of the value is compared for bucket fields.
+func (f ConditionField) compile() matchFunc {
+	name, typ, bucket := f.Name, f.Type, f.Bucket
+	cmp := f.comparer()
+
+	return func(values []ConditionValue) bool {
+		for _, v := range values {
+			if v.Name != name {
+				continue
+			}
+
+			value := v.Value
+			if bucket != nil {
+				if typ != IntType {
+					continue
+				}
+				value = bucket.Of(v)
+			} else if v.Type != typ {
+				continue
+			}
+
+			return cmp(value)
+		}
+
+		return false
+	}
+}
+
+// comparer returns a function comparing a value with the field value,
+// according to the field operator
+func (f ConditionField) comparer() func(v interface{}) bool {
+	want := f.Value
+
+	if (f.Type == SemverType || f.Type == TimeType) && (f.Op == EqOp || f.Op == NeOp) {
+		// Versions differing by their build metadata, and times by their
+		// location, are equal
+		order, equal := f.orderer(), f.Op == EqOp
+		return func(v interface{}) bool {
+			o, ok := order(v)
+			return ok && (o == 0) == equal
+		}
+	}
+
+	switch f.Op {
+	case NeOp:
+		return func(v interface{}) bool { return v != want }
+	case LtOp, GtOp, LeOp, GeOp:
+		lt, gt := LtOp, GtOp
+		if f.reversedOrder() {
+			lt, gt = GtOp, LtOp
 		}
 
+		var accept func(order int) bool
 		switch f.Op {
-		case NeOp:
-			return f.ne(v)
-		case LtOp:
-			return f.lt(v)
-		case GtOp:
-			return f.gt(v)
+		case lt:
+			accept = func(order int) bool { return order < 0 }
+		case gt:
+			accept = func(order int) bool { return order > 0 }
+		case LeOp:
+			accept = func(order int) bool { return order <= 0 }
 		default:
-			return f.eq(v)
+			accept = func(order int) bool { return order >= 0 }
 		}
+
+		order := f.orderer()
+		return func(v interface{}) bool {
+			o, ok := order(v)
+			return ok && accept(o)
+		}
+	case MatchesOp, LikeOp:
+		re := f.re
+		if re == nil {
+			var err error
+			if re, err = f.pattern(); err != nil {
+				return func(interface{}) bool { return false }
+			}
+		}
+		return func(v interface{}) bool {
+			s, ok := v.(string)
+			return ok && re.MatchString(s)
+		}
+	case StartsWithOp, EndsWithOp, ContainsOp:
+		w, _ := want.(string)
+		has := strings.Contains
+		if f.Op == StartsWithOp {
+			has = strings.HasPrefix
+		} else if f.Op == EndsWithOp {
+			has = strings.HasSuffix
+		}
+		return func(v interface{}) bool {
+			s, ok := v.(string)
+			return ok && has(s, w)
+		}
+	case InOp:
+		return f.contains()
+	case NotInOp:
+		contains := f.contains()
+		return func(v interface{}) bool { return !contains(v) }
+	default:
+		return func(v interface{}) bool { return v == want }
+	}
+}
+
+// reversedOrder checks whether the field value is the left operand of the
+// comparison. This is the case for the < and > operators of plain int, float
+// and string values, whose stored conditions have always been evaluated this
+// way.
+func (f ConditionField) reversedOrder() bool {
+	if f.Bucket != nil {
+		return false
 	}
 
+	switch f.Type {
+	case IntType, FloatType, StringType:
+		return true
+	}
 	return false
 }
 
-func (f ConditionField) eq(v ConditionValue) bool {
-	return f.Value == v.Value
+// orderer returns a function comparing a value with the field value, as
+// compareValues does. Semantic versions are ordered by their precedence.
+func (f ConditionField) orderer() func(v interface{}) (int, bool) {
+	want := f.Value
+
+	if f.Type == SemverType {
+		s, _ := want.(string)
+		w, valid := parseSemver(s)
+
+		return func(v interface{}) (int, bool) {
+			s, ok := v.(string)
+			if !ok || !valid {
+				return 0, false
+			}
+
+			version, ok := parseSemver(s)
+			return compareSemver(version, w), ok
+		}
+	}
+
+	return func(v interface{}) (int, bool) {
+		return compareValues(v, want)
+	}
 }
 
-func (f ConditionField) ne(v ConditionValue) bool {
-	return f.Value != v.Value
+// compareValues returns -1, 0 or 1 if a is less than, equal to or greater
+// than b. Values of different types, or of types without an order such as
+// booleans, can't be compared.
+func compareValues(a, b interface{}) (int, bool) {
+	switch b := b.(type) {
+	case int64:
+		if a, ok := a.(int64); ok {
+			return compareOrdered(a < b, a > b), true
+		}
+	case float64:
+		if a, ok := a.(float64); ok {
+			return compareOrdered(a < b, a > b), true
+		}
+	case string:
+		if a, ok := a.(string); ok {
+			return compareOrdered(a < b, a > b), true
+		}
+	case time.Time:
+		if a, ok := a.(time.Time); ok {
+			return compareOrdered(a.Before(b), a.After(b)), true
+		}
+	}
+
+	return 0, false
 }
 
-func (f ConditionField) lt(v ConditionValue) bool {
-	switch f.Type {
-	case IntType:
-		return f.Value.(int64) < v.Value.(int64)
-	case FloatType:
-		return f.Value.(float64) < v.Value.(float64)
-	case BoolType:
-		return false
-	case StringType:
-		return f.Value.(string) < v.Value.(string)
+func compareOrdered(less, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
 	}
-	return false
+	return 0
 }
 
-func (f ConditionField) gt(v ConditionValue) bool {
-	switch f.Type {
-	case IntType:
-		return f.Value.(int64) > v.Value.(int64)
-	case FloatType:
-		return f.Value.(float64) > v.Value.(float64)
-	case BoolType:
+// listSetSize is the list size above which list elements are looked up in a
+// set instead of scanned
+const listSetSize = 8
+
+// contains returns a function checking whether a value is an element of the
+// list field value. An invalid list contains nothing.
+func (f ConditionField) contains() func(v interface{}) bool {
+	elems, _ := f.list()
+
+	if len(elems) > listSetSize {
+		set := make(map[interface{}]struct{}, len(elems))
+		for _, e := range elems {
+			set[e] = struct{}{}
+		}
+
+		return func(v interface{}) bool {
+			_, ok := set[v]
+			return ok
+		}
+	}
+
+	return func(v interface{}) bool {
+		for _, e := range elems {
+			if e == v {
+				return true
+			}
+		}
 		return false
-	case StringType:
-		return f.Value.(string) > v.Value.(string)
 	}
-	return false
+}
+
+// withPattern returns a copy of a matches or like field with its compiled
+// pattern. Invalid patterns are left uncompiled.
+func (f ConditionField) withPattern() ConditionField {
+	if (f.Op == MatchesOp || f.Op == LikeOp) && f.re == nil {
+		f.re, _ = f.pattern()
+	}
+
+	return f
+}
+
+// pattern compiles the regular expression of a matches or like field
+func (f ConditionField) pattern() (*regexp.Regexp, error) {
+	p, ok := f.Value.(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid string type for value %T", f.Value)
+	}
+
+	if f.Op == LikeOp {
+		p = globRegexp(p)
+	}
+
+	return regexp.Compile(p)
+}
+
+// globRegexp converts a glob pattern to an anchored regular expression. The *
+// wildcard matches any text, ? matches a single character, and a backslash
+// escapes the next character.
+func globRegexp(glob string) string {
+	var b strings.Builder
+	b.WriteString("^(?s:")
+
+	var escaped bool
+	for _, r := range glob {
+		switch {
+		case escaped:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '*':
+			b.WriteString(".*")
+		case r == '?':
+			b.WriteRune('.')
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	if escaped {
+		b.WriteString(regexp.QuoteMeta("\\"))
+	}
+
+	b.WriteString(")$")
+
+	return b.String()
 }
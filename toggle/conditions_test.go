@@ -1,9 +1,13 @@
 package toggle_test
 
 import (
+	"encoding/json"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/globusdigital/feature-toggles/toggle"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestCondition_Match(t *testing.T) {
@@ -193,6 +197,288 @@ func TestCondition_Match(t *testing.T) {
 			{Name: "field", Type: toggle.StringType, Value: "10"},
 		}},
 
+		{name: "bucket string", c: toggle.Condition{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Name: "userID", Type: toggle.IntType, Value: int64(12)}, Op: toggle.LtOp, Bucket: &toggle.Bucket{Seed: "seed"}},
+		}}, values: []toggle.ConditionValue{
+			{Name: "userID", Type: toggle.StringType, Value: "user-1"},
+		}, want: true},
+
+		{name: "bucket string - false", c: toggle.Condition{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Name: "userID", Type: toggle.IntType, Value: int64(11)}, Op: toggle.LtOp, Bucket: &toggle.Bucket{Seed: "seed"}},
+		}}, values: []toggle.ConditionValue{
+			{Name: "userID", Type: toggle.StringType, Value: "user-1"},
+		}},
+
+		{name: "bucket int", c: toggle.Condition{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Name: "userID", Type: toggle.IntType, Value: int64(50)}, Op: toggle.GtOp, Bucket: &toggle.Bucket{}},
+		}}, values: []toggle.ConditionValue{
+			{Name: "userID", Type: toggle.IntType, Value: int64(42)},
+		}, want: true},
+
+		{name: "bucket missing value", c: toggle.Condition{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Name: "userID", Type: toggle.IntType, Value: int64(100)}, Op: toggle.LtOp, Bucket: &toggle.Bucket{}},
+		}}, values: []toggle.ConditionValue{
+			{Name: "tenant", Type: toggle.StringType, Value: "user-1"},
+		}},
+
+		{name: "in", c: toggle.Condition{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Name: "tenant", Type: toggle.StringType, Value: []string{"a", "b"}}, Op: toggle.InOp},
+		}}, values: []toggle.ConditionValue{
+			{Name: "tenant", Type: toggle.StringType, Value: "b"},
+		}, want: true},
+
+		{name: "in - false", c: toggle.Condition{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Name: "tenant", Type: toggle.StringType, Value: []string{"a", "b"}}, Op: toggle.InOp},
+		}}, values: []toggle.ConditionValue{
+			{Name: "tenant", Type: toggle.StringType, Value: "c"},
+		}},
+
+		{name: "in large", c: toggle.Condition{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Name: "userID", Type: toggle.IntType, Value: []int64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}}, Op: toggle.InOp},
+		}}, values: []toggle.ConditionValue{
+			{Name: "userID", Type: toggle.IntType, Value: int64(11)},
+		}, want: true},
+
+		{name: "in large - false", c: toggle.Condition{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Name: "userID", Type: toggle.IntType, Value: []int64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}}, Op: toggle.InOp},
+		}}, values: []toggle.ConditionValue{
+			{Name: "userID", Type: toggle.IntType, Value: int64(13)},
+		}},
+
+		{name: "in other type", c: toggle.Condition{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Name: "userID", Type: toggle.IntType, Value: []int64{1, 2}}, Op: toggle.InOp},
+		}}, values: []toggle.ConditionValue{
+			{Name: "userID", Type: toggle.StringType, Value: "1"},
+		}},
+
+		{name: "not in", c: toggle.Condition{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Name: "tenant", Type: toggle.StringType, Value: []string{"a", "b"}}, Op: toggle.NotInOp},
+		}}, values: []toggle.ConditionValue{
+			{Name: "tenant", Type: toggle.StringType, Value: "c"},
+		}, want: true},
+
+		{name: "not in - false", c: toggle.Condition{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Name: "tenant", Type: toggle.StringType, Value: []string{"a", "b"}}, Op: toggle.NotInOp},
+		}}, values: []toggle.ConditionValue{
+			{Name: "tenant", Type: toggle.StringType, Value: "a"},
+		}},
+
+		{name: "not in missing value", c: toggle.Condition{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Name: "tenant", Type: toggle.StringType, Value: []string{"a", "b"}}, Op: toggle.NotInOp},
+		}}, values: []toggle.ConditionValue{
+			{Name: "userID", Type: toggle.IntType, Value: int64(1)},
+		}},
+
+		{name: "bucket in", c: toggle.Condition{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Name: "userID", Type: toggle.IntType, Value: []int64{10, 11}}, Op: toggle.InOp, Bucket: &toggle.Bucket{Seed: "seed"}},
+		}}, values: []toggle.ConditionValue{
+			{Name: "userID", Type: toggle.StringType, Value: "user-1"},
+		}, want: true},
+
+		{name: "<= equal", c: toggle.Condition{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Name: "version", Type: toggle.IntType, Value: int64(3)}, Op: toggle.LeOp},
+		}}, values: []toggle.ConditionValue{
+			{Name: "version", Type: toggle.IntType, Value: int64(3)},
+		}, want: true},
+
+		{name: "<= - false", c: toggle.Condition{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Name: "version", Type: toggle.IntType, Value: int64(3)}, Op: toggle.LeOp},
+		}}, values: []toggle.ConditionValue{
+			{Name: "version", Type: toggle.IntType, Value: int64(4)},
+		}},
+
+		{name: ">= float", c: toggle.Condition{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Name: "ratio", Type: toggle.FloatType, Value: 0.5}, Op: toggle.GeOp},
+		}}, values: []toggle.ConditionValue{
+			{Name: "ratio", Type: toggle.FloatType, Value: 0.75},
+		}, want: true},
+
+		{name: ">= string - false", c: toggle.Condition{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Name: "tenant", Type: toggle.StringType, Value: "b"}, Op: toggle.GeOp},
+		}}, values: []toggle.ConditionValue{
+			{Name: "tenant", Type: toggle.StringType, Value: "a"},
+		}},
+
+		{name: ">= bool", c: toggle.Condition{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Name: "beta", Type: toggle.BoolType, Value: true}, Op: toggle.GeOp},
+		}}, values: []toggle.ConditionValue{
+			{Name: "beta", Type: toggle.BoolType, Value: true},
+		}},
+
+		{name: "not", c: toggle.Condition{Not: true, Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Name: "tenant", Type: toggle.StringType, Value: "a"}},
+		}}, values: []toggle.ConditionValue{
+			{Name: "tenant", Type: toggle.StringType, Value: "b"},
+		}, want: true},
+
+		{name: "not - false", c: toggle.Condition{Not: true, Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Name: "tenant", Type: toggle.StringType, Value: "a"}},
+		}}, values: []toggle.ConditionValue{
+			{Name: "tenant", Type: toggle.StringType, Value: "a"},
+		}},
+
+		{name: "nested not", c: toggle.Condition{Conditions: []toggle.Condition{
+			{Op: toggle.OrOp, Not: true, Fields: []toggle.ConditionField{
+				{ConditionValue: toggle.ConditionValue{Name: "tenant", Type: toggle.StringType, Value: "a"}},
+				{ConditionValue: toggle.ConditionValue{Name: "tenant", Type: toggle.StringType, Value: "b"}},
+			}},
+		}, Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Name: "userID", Type: toggle.IntType, Value: int64(10)}, Op: toggle.LtOp},
+		}}, values: []toggle.ConditionValue{
+			{Name: "tenant", Type: toggle.StringType, Value: "c"},
+			{Name: "userID", Type: toggle.IntType, Value: int64(20)},
+		}, want: true},
+
+		{name: "matches", c: toggle.Condition{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Name: "path", Type: toggle.StringType, Value: "^/api/v\\d+/"}, Op: toggle.MatchesOp},
+		}}, values: []toggle.ConditionValue{
+			{Name: "path", Type: toggle.StringType, Value: "/api/v2/users"},
+		}, want: true},
+
+		{name: "matches - false", c: toggle.Condition{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Name: "path", Type: toggle.StringType, Value: "^/api/v\\d+/"}, Op: toggle.MatchesOp},
+		}}, values: []toggle.ConditionValue{
+			{Name: "path", Type: toggle.StringType, Value: "/api/beta/users"},
+		}},
+
+		{name: "like", c: toggle.Condition{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Name: "path", Type: toggle.StringType, Value: "/api/v2/*"}, Op: toggle.LikeOp},
+		}}, values: []toggle.ConditionValue{
+			{Name: "path", Type: toggle.StringType, Value: "/api/v2/users/1"},
+		}, want: true},
+
+		{name: "like single", c: toggle.Condition{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Name: "path", Type: toggle.StringType, Value: "/api/v?/users"}, Op: toggle.LikeOp},
+		}}, values: []toggle.ConditionValue{
+			{Name: "path", Type: toggle.StringType, Value: "/api/v2/users"},
+		}, want: true},
+
+		{name: "like - false", c: toggle.Condition{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Name: "path", Type: toggle.StringType, Value: "/api/v2/*"}, Op: toggle.LikeOp},
+		}}, values: []toggle.ConditionValue{
+			{Name: "path", Type: toggle.StringType, Value: "/api/v3/users"},
+		}},
+
+		{name: "like escaped", c: toggle.Condition{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Name: "path", Type: toggle.StringType, Value: "/api/\\*"}, Op: toggle.LikeOp},
+		}}, values: []toggle.ConditionValue{
+			{Name: "path", Type: toggle.StringType, Value: "/api/*"},
+		}, want: true},
+
+		{name: "like escaped - false", c: toggle.Condition{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Name: "path", Type: toggle.StringType, Value: "/api/\\*"}, Op: toggle.LikeOp},
+		}}, values: []toggle.ConditionValue{
+			{Name: "path", Type: toggle.StringType, Value: "/api/v2"},
+		}},
+
+		{name: "startsWith", c: toggle.Condition{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Name: "email", Type: toggle.StringType, Value: "admin"}, Op: toggle.StartsWithOp},
+		}}, values: []toggle.ConditionValue{
+			{Name: "email", Type: toggle.StringType, Value: "admin@example.com"},
+		}, want: true},
+
+		{name: "startsWith - false", c: toggle.Condition{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Name: "email", Type: toggle.StringType, Value: "admin"}, Op: toggle.StartsWithOp},
+		}}, values: []toggle.ConditionValue{
+			{Name: "email", Type: toggle.StringType, Value: "user@example.com"},
+		}},
+
+		{name: "endsWith", c: toggle.Condition{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Name: "email", Type: toggle.StringType, Value: "@example.com"}, Op: toggle.EndsWithOp},
+		}}, values: []toggle.ConditionValue{
+			{Name: "email", Type: toggle.StringType, Value: "admin@example.com"},
+		}, want: true},
+
+		{name: "endsWith - false", c: toggle.Condition{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Name: "email", Type: toggle.StringType, Value: "@example.com"}, Op: toggle.EndsWithOp},
+		}}, values: []toggle.ConditionValue{
+			{Name: "email", Type: toggle.StringType, Value: "admin@example.org"},
+		}},
+
+		{name: "contains", c: toggle.Condition{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Name: "plan", Type: toggle.StringType, Value: "pro"}, Op: toggle.ContainsOp},
+		}}, values: []toggle.ConditionValue{
+			{Name: "plan", Type: toggle.StringType, Value: "enterprise-pro-2"},
+		}, want: true},
+
+		{name: "contains - false", c: toggle.Condition{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Name: "plan", Type: toggle.StringType, Value: "pro"}, Op: toggle.ContainsOp},
+		}}, values: []toggle.ConditionValue{
+			{Name: "plan", Type: toggle.StringType, Value: "free"},
+		}},
+
+		{name: "contains other type", c: toggle.Condition{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Name: "plan", Type: toggle.StringType, Value: "1"}, Op: toggle.ContainsOp},
+		}}, values: []toggle.ConditionValue{
+			{Name: "plan", Type: toggle.IntType, Value: int64(1)},
+		}},
+
+		{name: ">= semver", c: toggle.Condition{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Name: "appVersion", Type: toggle.SemverType, Value: "4.12.0"}, Op: toggle.GeOp},
+		}}, values: []toggle.ConditionValue{
+			{Name: "appVersion", Type: toggle.SemverType, Value: "4.9.0"},
+		}},
+
+		{name: ">= semver minor", c: toggle.Condition{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Name: "appVersion", Type: toggle.SemverType, Value: "4.9.0"}, Op: toggle.GeOp},
+		}}, values: []toggle.ConditionValue{
+			{Name: "appVersion", Type: toggle.SemverType, Value: "4.12.0"},
+		}, want: true},
+
+		{name: "< semver pre-release", c: toggle.Condition{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Name: "appVersion", Type: toggle.SemverType, Value: "4.12.0"}, Op: toggle.LtOp},
+		}}, values: []toggle.ConditionValue{
+			{Name: "appVersion", Type: toggle.SemverType, Value: "4.12.0-beta.2"},
+		}, want: true},
+
+		{name: "> semver pre-release", c: toggle.Condition{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Name: "appVersion", Type: toggle.SemverType, Value: "4.12.0-beta.2"}, Op: toggle.GtOp},
+		}}, values: []toggle.ConditionValue{
+			{Name: "appVersion", Type: toggle.SemverType, Value: "4.12.0-beta.11"},
+		}, want: true},
+
+		{name: "== semver build", c: toggle.Condition{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Name: "appVersion", Type: toggle.SemverType, Value: "4.12.0"}, Op: toggle.EqOp},
+		}}, values: []toggle.ConditionValue{
+			{Name: "appVersion", Type: toggle.SemverType, Value: "4.12.0+build.7"},
+		}, want: true},
+
+		{name: "!= semver", c: toggle.Condition{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Name: "appVersion", Type: toggle.SemverType, Value: "4.12.0"}, Op: toggle.NeOp},
+		}}, values: []toggle.ConditionValue{
+			{Name: "appVersion", Type: toggle.SemverType, Value: "4.12.1"},
+		}, want: true},
+
+		{name: "<= semver invalid value", c: toggle.Condition{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Name: "appVersion", Type: toggle.SemverType, Value: "4.12.0"}, Op: toggle.LeOp},
+		}}, values: []toggle.ConditionValue{
+			{Name: "appVersion", Type: toggle.SemverType, Value: "4.12"},
+		}},
+
+		{name: "semver string value", c: toggle.Condition{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Name: "appVersion", Type: toggle.SemverType, Value: "4.12.0"}, Op: toggle.GeOp},
+		}}, values: []toggle.ConditionValue{
+			{Name: "appVersion", Type: toggle.StringType, Value: "5.0.0"},
+		}},
+
+		{name: "< time", c: toggle.Condition{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Name: "now", Type: toggle.TimeType, Value: time.Date(2021, 3, 1, 17, 0, 0, 0, time.UTC)}, Op: toggle.LtOp},
+		}}, values: []toggle.ConditionValue{
+			{Name: "now", Type: toggle.TimeType, Value: time.Date(2021, 3, 1, 16, 59, 59, 0, time.UTC)},
+		}, want: true},
+
+		{name: ">= time", c: toggle.Condition{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Name: "now", Type: toggle.TimeType, Value: time.Date(2021, 3, 1, 9, 0, 0, 0, time.UTC)}, Op: toggle.GeOp},
+		}}, values: []toggle.ConditionValue{
+			{Name: "now", Type: toggle.TimeType, Value: time.Date(2021, 3, 1, 8, 0, 0, 0, time.UTC)},
+		}},
+
+		{name: "== time location", c: toggle.Condition{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Name: "now", Type: toggle.TimeType, Value: time.Date(2021, 3, 1, 9, 0, 0, 0, time.UTC)}, Op: toggle.EqOp},
+		}}, values: []toggle.ConditionValue{
+			{Name: "now", Type: toggle.TimeType, Value: time.Date(2021, 3, 1, 11, 0, 0, 0, time.FixedZone("EET", 2*60*60))},
+		}, want: true},
+
 		{name: "real example 1", c: toggle.Condition{Fields: []toggle.ConditionField{
 			{ConditionValue: toggle.ConditionValue{Name: "workspace", Type: 3, Value: "stage"}},
 		}}, values: []toggle.ConditionValue{
@@ -210,6 +496,49 @@ func TestCondition_Match(t *testing.T) {
 	}
 }
 
+func TestCondition_Match_compiled(t *testing.T) {
+	a := assert.New(t)
+
+	built := toggle.Condition{Fields: []toggle.ConditionField{
+		{ConditionValue: toggle.ConditionValue{Name: "path", Type: toggle.StringType, Value: "^/api/v\\d+/"}, Op: toggle.MatchesOp},
+		{ConditionValue: toggle.ConditionValue{Name: "path", Type: toggle.StringType, Value: "/api/*/users"}, Op: toggle.LikeOp},
+	}}
+	parsed, err := toggle.ParseCondition(strings.NewReader(`path matches '^/api/v\d+/' && path like '/api/*/users'`))
+	a.NoError(err)
+	var decoded toggle.Condition
+	a.NoError(json.Unmarshal([]byte(`{"fields": [
+		{"name": "path", "type": 3, "value": "^/api/v\\d+/", "op": 8},
+		{"name": "path", "type": 3, "value": "/api/*/users", "op": 9}
+	]}`), &decoded))
+
+	values := []toggle.ConditionValue{{Name: "path", Type: toggle.StringType, Value: "/api/v2/users"}}
+	allocs := func(c toggle.Condition) float64 {
+		return testing.AllocsPerRun(100, func() {
+			a.True(c.Match(values))
+		})
+	}
+
+	a.Less(allocs(parsed), allocs(built), "parsed")
+	a.Less(allocs(decoded), allocs(built), "decoded")
+}
+
+func TestBucket_Of(t *testing.T) {
+	a := assert.New(t)
+
+	b := toggle.Bucket{Seed: "seed"}
+	a.Equal(int64(11), b.Of(toggle.ConditionValue{Name: "userID", Type: toggle.StringType, Value: "user-1"}))
+	a.Equal(int64(88), b.Of(toggle.ConditionValue{Name: "userID", Type: toggle.IntType, Value: int64(42)}))
+	a.NotEqual(b.Of(toggle.ConditionValue{Value: "user-1"}), toggle.Bucket{Seed: "other"}.Of(toggle.ConditionValue{Value: "user-1"}))
+
+	var rolledOut int
+	for i := int64(0); i < 10000; i++ {
+		if b.Of(toggle.ConditionValue{Type: toggle.IntType, Value: i}) < 25 {
+			rolledOut++
+		}
+	}
+	a.InDelta(2500, rolledOut, 150)
+}
+
 func TestConditionValue_Validate(t *testing.T) {
 	type fields struct {
 		Name  string
@@ -231,6 +560,11 @@ func TestConditionValue_Validate(t *testing.T) {
 		{name: "valid bool type", fields: fields{Type: toggle.BoolType, Value: true}},
 		{name: "invalid string type", fields: fields{Type: toggle.StringType, Value: 43}, wantErr: true},
 		{name: "valid string type", fields: fields{Type: toggle.StringType, Value: "43.5"}},
+		{name: "invalid semver type", fields: fields{Type: toggle.SemverType, Value: 43}, wantErr: true},
+		{name: "invalid semver", fields: fields{Type: toggle.SemverType, Value: "4.12"}, wantErr: true},
+		{name: "valid semver", fields: fields{Type: toggle.SemverType, Value: "v4.12.0-rc.1+build.5"}},
+		{name: "invalid time type", fields: fields{Type: toggle.TimeType, Value: "2021-03-01T09:00:00Z"}, wantErr: true},
+		{name: "valid time", fields: fields{Type: toggle.TimeType, Value: time.Now()}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -262,6 +596,34 @@ func TestCondition_Validate(t *testing.T) {
 			{ConditionValue: toggle.ConditionValue{Type: toggle.IntType, Value: int64(42)}},
 			{ConditionValue: toggle.ConditionValue{Type: toggle.IntType, Value: float64(42)}},
 		}}, wantErr: true},
+		{name: "invalid bucket type", fields: fields{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Type: toggle.FloatType, Value: float64(42)}, Bucket: &toggle.Bucket{}},
+		}}, wantErr: true},
+		{name: "valid bucket", fields: fields{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Type: toggle.IntType, Value: int64(42)}, Bucket: &toggle.Bucket{}},
+		}}},
+		{name: "valid list", fields: fields{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Type: toggle.StringType, Value: []string{"a"}}, Op: toggle.InOp},
+		}}},
+		{name: "invalid list type", fields: fields{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Type: toggle.IntType, Value: []string{"a"}}, Op: toggle.NotInOp},
+		}}, wantErr: true},
+		{name: "invalid list", fields: fields{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Type: toggle.StringType, Value: "a"}, Op: toggle.InOp},
+		}}, wantErr: true},
+		{name: "list without in", fields: fields{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Type: toggle.StringType, Value: []string{"a"}}},
+		}}, wantErr: true},
+		{name: "valid pattern", fields: fields{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Type: toggle.StringType, Value: `^/api/v\d+/`}, Op: toggle.MatchesOp},
+			{ConditionValue: toggle.ConditionValue{Type: toggle.StringType, Value: "/api/[v2]*"}, Op: toggle.LikeOp},
+		}}},
+		{name: "invalid pattern", fields: fields{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Type: toggle.StringType, Value: "^/api/(v2"}, Op: toggle.MatchesOp},
+		}}, wantErr: true},
+		{name: "invalid text type", fields: fields{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Type: toggle.IntType, Value: int64(1)}, Op: toggle.ContainsOp},
+		}}, wantErr: true},
 		{name: "invalid condition", fields: fields{Conditions: []toggle.Condition{
 			{Fields: []toggle.ConditionField{{ConditionValue: toggle.ConditionValue{Type: toggle.IntType, Value: int64(50)}}}},
 			{Fields: []toggle.ConditionField{{ConditionValue: toggle.ConditionValue{Type: toggle.IntType, Value: float64(50)}}}},
@@ -286,6 +648,7 @@ func TestCondition_String(t *testing.T) {
 		Op         toggle.ConditionOp
 		Conditions []toggle.Condition
 		Fields     []toggle.ConditionField
+		Not        bool
 	}
 	tests := []struct {
 		name   string
@@ -308,6 +671,15 @@ func TestCondition_String(t *testing.T) {
 		{name: "complete", fields: fields{Fields: []toggle.ConditionField{
 			{ConditionValue: toggle.ConditionValue{Name: "invalid", Type: toggle.IntType, Value: "some value"}, Op: toggle.GtOp},
 		}}, want: "(invalid > int(some value))"},
+
+		{name: "bucket", fields: fields{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Name: "userID", Type: toggle.IntType, Value: int64(25)}, Op: toggle.LtOp, Bucket: &toggle.Bucket{Seed: "seed"}},
+		}}, want: `(bucket(userID, "seed") < int(25))`},
+
+		{name: "not", fields: fields{Op: toggle.OrOp, Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Name: "version", Type: toggle.IntType, Value: int64(3)}, Op: toggle.GeOp},
+			{ConditionValue: toggle.ConditionValue{Name: "ratio", Type: toggle.FloatType, Value: 0.5}, Op: toggle.LeOp},
+		}, Not: true}, want: "!(version >= int(3) || ratio <= float(0.5))"},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -315,6 +687,7 @@ func TestCondition_String(t *testing.T) {
 				Op:         tt.fields.Op,
 				Conditions: tt.fields.Conditions,
 				Fields:     tt.fields.Fields,
+				Not:        tt.fields.Not,
 			}
 			if got := c.String(); got != tt.want {
 				t.Errorf("Condition.String() = %v, want %v", got, tt.want)
@@ -0,0 +1,114 @@
+package toggle
+
+import (
+	"context"
+	"time"
+)
+
+type (
+	valuesCtxKey    struct{}
+	overridesCtxKey struct{}
+)
+
+// WithValues returns a copy of the context carrying the given condition
+// values, along with any values of the parent context. Values with the same
+// name as a parent value override it. The value types are inferred as they
+// are by For.
+func WithValues(ctx context.Context, values ...ConditionValue) context.Context {
+	values = mergeValues(ValuesFromContext(ctx), inferTypes(values))
+
+	return context.WithValue(ctx, valuesCtxKey{}, values)
+}
+
+// ValuesFromContext returns the condition values carried by the context
+func ValuesFromContext(ctx context.Context) []ConditionValue {
+	values, _ := ctx.Value(valuesCtxKey{}).([]ConditionValue)
+
+	return values
+}
+
+// WithOverrides returns a copy of the context carrying raw flag values, which
+// override the stored flags when evaluated with the context. Overrides of the
+// parent context with the same names are replaced. The override of a
+// multivariate flag selects the variant with the same name as the raw value.
+func WithOverrides(ctx context.Context, overrides map[string]string) context.Context {
+	merged := map[string]string{}
+	for name, raw := range OverridesFromContext(ctx) {
+		merged[name] = raw
+	}
+	for name, raw := range overrides {
+		merged[NormalizeName(name)] = raw
+	}
+
+	return context.WithValue(ctx, overridesCtxKey{}, merged)
+}
+
+// OverridesFromContext returns the flag overrides carried by the context, keyed
+// by the normalized flag name
+func OverridesFromContext(ctx context.Context) map[string]string {
+	overrides, _ := ctx.Value(overridesCtxKey{}).(map[string]string)
+
+	return overrides
+}
+
+// fromContext sets the condition values and overrides of the context. The
+// values take precedence over the client values and are overridden by the
+// call values.
+func fromContext(ctx context.Context) Option {
+	return func(o *getOptions) {
+		o.ctxValues = ValuesFromContext(ctx)
+		o.overrides = OverridesFromContext(ctx)
+	}
+}
+
+func ctxOptions(ctx context.Context, opts []Option) []Option {
+	return append([]Option{fromContext(ctx)}, opts...)
+}
+
+// GetCtx returns the boolean flag value, using the condition values of the
+// context. See Get.
+func (c *Client) GetCtx(ctx context.Context, name string, opts ...Option) bool {
+	return c.Get(name, ctxOptions(ctx, opts)...)
+}
+
+// GetRawCtx returns the raw string flag value, using the condition values of
+// the context. See GetRaw.
+func (c *Client) GetRawCtx(ctx context.Context, name string, opts ...Option) string {
+	return c.GetRaw(name, ctxOptions(ctx, opts)...)
+}
+
+// GetIntCtx returns the flag value parsed as an integer, using the condition
+// values of the context. See GetInt.
+func (c *Client) GetIntCtx(ctx context.Context, name string, def int64, opts ...Option) int64 {
+	return c.GetInt(name, def, ctxOptions(ctx, opts)...)
+}
+
+// GetFloatCtx returns the flag value parsed as a float, using the condition
+// values of the context. See GetFloat.
+func (c *Client) GetFloatCtx(ctx context.Context, name string, def float64, opts ...Option) float64 {
+	return c.GetFloat(name, def, ctxOptions(ctx, opts)...)
+}
+
+// GetDurationCtx returns the flag value parsed as a duration, using the
+// condition values of the context. See GetDuration.
+func (c *Client) GetDurationCtx(ctx context.Context, name string, def time.Duration, opts ...Option) time.Duration {
+	return c.GetDuration(name, def, ctxOptions(ctx, opts)...)
+}
+
+// GetJSONCtx unmarshals the raw flag value into v, using the condition values
+// of the context. See GetJSON.
+func (c *Client) GetJSONCtx(ctx context.Context, name string, v interface{}, opts ...Option) {
+	c.GetJSON(name, v, ctxOptions(ctx, opts)...)
+}
+
+// VariantCtx returns the variant of a multivariate flag, using the condition
+// values of the context. See Variant.
+func (c *Client) VariantCtx(ctx context.Context, name string, opts ...Option) Variant {
+	return c.Variant(name, ctxOptions(ctx, opts)...)
+}
+
+// EvaluateCtx evaluates the flag using the condition values of the context.
+// See Evaluate.
+func (c *Client) EvaluateCtx(ctx context.Context, name string, opts ...Option) EvaluationDetail {
+	return c.Evaluate(name, ctxOptions(ctx, opts)...)
+}
@@ -0,0 +1,139 @@
+package toggle_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/globusdigital/feature-toggles/toggle"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithValues(t *testing.T) {
+	a := assert.New(t)
+
+	a.Empty(toggle.ValuesFromContext(context.Background()))
+
+	parent := toggle.WithValues(context.Background(), toggle.ConditionValue{Name: "userID", Value: int64(1)}, toggle.ConditionValue{Name: "tenant", Value: "t1"})
+	ctx := toggle.WithValues(parent, toggle.ConditionValue{Name: "userID", Value: int64(2)}, toggle.ConditionValue{Name: "beta", Value: true},
+		toggle.ConditionValue{Name: "appVersion", Type: toggle.SemverType, Value: "4.12.0"})
+
+	a.Equal([]toggle.ConditionValue{
+		{Name: "userID", Type: toggle.IntType, Value: int64(1)},
+		{Name: "tenant", Type: toggle.StringType, Value: "t1"},
+	}, toggle.ValuesFromContext(parent))
+	a.Equal([]toggle.ConditionValue{
+		{Name: "userID", Type: toggle.IntType, Value: int64(2)},
+		{Name: "tenant", Type: toggle.StringType, Value: "t1"},
+		{Name: "beta", Type: toggle.BoolType, Value: true},
+		{Name: "appVersion", Type: toggle.SemverType, Value: "4.12.0"},
+	}, toggle.ValuesFromContext(ctx))
+
+	a.Panics(func() {
+		toggle.WithValues(ctx, toggle.ConditionValue{Name: "userID", Value: 1})
+	})
+}
+
+func TestClient_GetCtx(t *testing.T) {
+	tenant := func(v string) toggle.ConditionValue {
+		return toggle.ConditionValue{Name: "tenant", Value: v}
+	}
+
+	tests := []struct {
+		name    string
+		client  []toggle.ConditionValue
+		ctx     []toggle.ConditionValue
+		opts    []toggle.Option
+		wantRaw string
+	}{
+		{name: "no values", wantRaw: "default"},
+		{name: "client", client: []toggle.ConditionValue{tenant("client")}, wantRaw: "client"},
+		{name: "context", ctx: []toggle.ConditionValue{tenant("ctx")}, wantRaw: "ctx"},
+		{name: "context over client", client: []toggle.ConditionValue{tenant("client")}, ctx: []toggle.ConditionValue{tenant("ctx")}, wantRaw: "ctx"},
+		{name: "call over context", client: []toggle.ConditionValue{tenant("client")}, ctx: []toggle.ConditionValue{tenant("ctx")}, opts: []toggle.Option{toggle.ForString("tenant", "call")}, wantRaw: "call"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := assert.New(t)
+
+			c := toggle.New("serv1", toggle.For(tt.client...))
+			c.SetFlags(toggle.Flag{Name: "checkout", ServiceName: "serv1", RawValue: "default", Rules: []toggle.Rule{
+				{Condition: condition(t, `tenant == "client"`), RawValue: "client"},
+				{Condition: condition(t, `tenant == "ctx"`), RawValue: "ctx"},
+				{Condition: condition(t, `tenant == "call"`), RawValue: "call"},
+			}})
+
+			ctx := toggle.WithValues(context.Background(), tt.ctx...)
+
+			a.Equal(tt.wantRaw, c.GetRawCtx(ctx, "checkout", tt.opts...))
+			a.Equal(tt.wantRaw, c.EvaluateCtx(ctx, "checkout", tt.opts...).RawValue)
+		})
+	}
+}
+
+func TestClient_GetCtx_typed(t *testing.T) {
+	a := assert.New(t)
+
+	c := toggle.New("serv1")
+	gated := func(name, raw string) toggle.Flag {
+		f := toggle.NewFlag(name, "serv1", raw)
+		f.Condition = condition(t, `userID == 1`)
+		return f
+	}
+	c.SetFlags(gated("enabled", "t"), gated("workers", "4"), gated("ratio", "0.5"), gated("timeout", "2s"), gated("config", `{"a":1}`))
+	button := gated("button", "t")
+	button.Variants, button.VariantBy = []toggle.Variant{
+		{ConditionValue: toggle.ConditionValue{Name: "blue", Type: toggle.StringType, Value: "#00f"}, Weight: 1},
+	}, "userID"
+	c.SetFlags(button)
+
+	ctx := toggle.WithValues(context.Background(), toggle.ConditionValue{Name: "userID", Value: int64(1)})
+
+	a.True(c.GetCtx(ctx, "enabled"))
+	a.False(c.GetCtx(context.Background(), "enabled"))
+	a.False(c.GetCtx(ctx, "enabled", toggle.ForInt("userID", 2)))
+	a.Equal(int64(4), c.GetIntCtx(ctx, "workers", 1))
+	a.Equal(0.5, c.GetFloatCtx(ctx, "ratio", 1))
+	a.Equal(2*time.Second, c.GetDurationCtx(ctx, "timeout", time.Second))
+	var config map[string]int
+	c.GetJSONCtx(ctx, "config", &config)
+	a.Equal(map[string]int{"a": 1}, config)
+	a.Equal("blue", c.VariantCtx(ctx, "button").Name)
+}
+
+func TestClient_WithOverrides(t *testing.T) {
+	a := assert.New(t)
+
+	c := toggle.New("serv1")
+	button := toggle.NewFlag("button", "serv1", "f")
+	button.Variants = []toggle.Variant{
+		{ConditionValue: toggle.ConditionValue{Name: "blue", Type: toggle.StringType, Value: "#00f"}, Weight: 1},
+		{ConditionValue: toggle.ConditionValue{Name: "red", Type: toggle.StringType, Value: "#f00"}, Weight: 1},
+	}
+	c.SetFlags(toggle.NewFlag("checkout", "serv1", "f"), button)
+
+	ctx := toggle.WithOverrides(context.Background(), map[string]string{"Checkout": "t", "button": "red", "missing": "4"})
+	a.Equal(map[string]string{"checkout": "t", "button": "red", "missing": "4"}, toggle.OverridesFromContext(ctx))
+
+	a.False(c.GetCtx(context.Background(), "checkout"))
+	a.True(c.GetCtx(ctx, "checkout"))
+	a.Equal(int64(4), c.GetIntCtx(ctx, "missing", 1))
+
+	d := c.EvaluateCtx(ctx, "checkout")
+	a.Equal(toggle.OverriddenReason, d.Reason)
+	a.Equal(toggle.OverrideSource, d.Source)
+	a.Equal("t", d.RawValue)
+
+	a.Equal("red", c.VariantCtx(ctx, "button").Name)
+	a.Equal("", c.VariantCtx(toggle.WithOverrides(ctx, map[string]string{"button": "green"}), "button").Name)
+}
+
+func condition(t *testing.T, expr string) toggle.Condition {
+	cond, err := toggle.ParseCondition(strings.NewReader(expr))
+	if err != nil {
+		t.Fatalf("Error parsing condition %q: %v", expr, err)
+	}
+
+	return cond
+}
@@ -0,0 +1,185 @@
+package toggle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// FlagType is the declared type of a flag value
+type FlagType string
+
+const (
+	StringFlag   FlagType = "string"
+	BoolFlag     FlagType = "bool"
+	IntFlag      FlagType = "int"
+	FloatFlag    FlagType = "float"
+	DurationFlag FlagType = "duration"
+	JSONFlag     FlagType = "json"
+)
+
+// Definition declares a flag of a service, along with its type, default value
+// and documentation
+type Definition struct {
+	Name        string `json:"name"`
+	ServiceName string `json:"service,omitempty"`
+
+	// Type is the type of the raw flag values. Untyped flags are strings.
+	Type FlagType `json:"type,omitempty"`
+	// Default is the raw value used when no flag matches, or its value isn't
+	// valid for the type
+	Default string `json:"default,omitempty"`
+
+	Description string `json:"description,omitempty"`
+	Owner       string `json:"owner,omitempty"`
+}
+
+// Registry holds the flag definitions of a service. Definitions are replaced
+// on write, so that lookups don't need locking.
+type Registry struct {
+	defs atomic.Value
+	mu   sync.Mutex
+}
+
+// DefaultRegistry is the registry used by Define, and by the clients without
+// the WithRegistry option
+var DefaultRegistry = NewRegistry()
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	r := &Registry{}
+	r.defs.Store(map[string]Definition{})
+
+	return r
+}
+
+// Define declares a flag in the DefaultRegistry. It is meant to be called
+// when initializing a package, and panics if the definition is invalid or
+// conflicts with an existing one.
+func Define(d Definition) Definition {
+	return DefaultRegistry.Define(d)
+}
+
+// Define declares a flag in the registry, and returns the normalized
+// definition. It panics if the definition is invalid or conflicts with an
+// existing one. Identical definitions may be repeated.
+func (r *Registry) Define(d Definition) Definition {
+	d = d.Normalized()
+	if err := d.Validate(); err != nil {
+		panic(fmt.Sprintf("Invalid flag definition %s: %v", d.Name, err))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := r.load()
+	if existing, ok := stored[d.Name]; ok {
+		if existing != d {
+			panic(fmt.Sprintf("Flag %s is already defined", d.Name))
+		}
+		return d
+	}
+
+	defs := make(map[string]Definition, len(stored)+1)
+	for name, def := range stored {
+		defs[name] = def
+	}
+	defs[d.Name] = d
+	r.defs.Store(defs)
+
+	return d
+}
+
+// Lookup returns the definition of the named flag
+func (r *Registry) Lookup(name string) (Definition, bool) {
+	d, ok := r.load()[NormalizeName(name)]
+	return d, ok
+}
+
+// Definitions returns all definitions, sorted by name
+func (r *Registry) Definitions() []Definition {
+	stored := r.load()
+	defs := make([]Definition, 0, len(stored))
+	for _, d := range stored {
+		defs = append(defs, d)
+	}
+
+	sort.Slice(defs, func(i, j int) bool {
+		return defs[i].Name < defs[j].Name
+	})
+
+	return defs
+}
+
+func (r *Registry) load() map[string]Definition {
+	defs, _ := r.defs.Load().(map[string]Definition)
+	return defs
+}
+
+// Normalized returns a copy of the definition with a normalized name and
+// service name
+func (d Definition) Normalized() Definition {
+	d.Name = NormalizeName(d.Name)
+	d.ServiceName = normalizeSerivceName(d.ServiceName)
+
+	return d
+}
+
+// Validate checks that the definition has a name, a known type and a default
+// value valid for it
+func (d Definition) Validate() error {
+	if d.Name == "" {
+		return errors.New("empty name")
+	}
+
+	switch d.Type {
+	case "", StringFlag, BoolFlag, IntFlag, FloatFlag, DurationFlag, JSONFlag:
+	default:
+		return fmt.Errorf("unknown type %q", string(d.Type))
+	}
+
+	if d.Default == "" {
+		return nil
+	}
+
+	if err := d.Type.Check(d.Default); err != nil {
+		return fmt.Errorf("invalid default: %v", err)
+	}
+
+	return nil
+}
+
+// Check returns an error if the raw value isn't valid for the type, or the
+// type is unknown
+func (t FlagType) Check(raw string) error {
+	var err error
+	switch t {
+	case "", StringFlag:
+	case BoolFlag:
+		switch strings.ToLower(raw) {
+		case "1", "y", "yes", "t", "true", "0", "n", "no", "f", "false":
+		default:
+			err = fmt.Errorf("invalid bool %q", raw)
+		}
+	case IntFlag:
+		_, err = strconv.ParseInt(raw, 10, 64)
+	case FloatFlag:
+		_, err = strconv.ParseFloat(raw, 64)
+	case DurationFlag:
+		_, err = time.ParseDuration(raw)
+	case JSONFlag:
+		if !json.Valid([]byte(raw)) {
+			err = fmt.Errorf("invalid json %q", raw)
+		}
+	default:
+		return fmt.Errorf("unknown type %q", string(t))
+	}
+
+	return err
+}
@@ -0,0 +1,130 @@
+package toggle_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/globusdigital/feature-toggles/api"
+	"github.com/globusdigital/feature-toggles/messaging"
+	"github.com/globusdigital/feature-toggles/storage"
+	"github.com/globusdigital/feature-toggles/toggle"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry_Define(t *testing.T) {
+	a := assert.New(t)
+
+	r := toggle.NewRegistry()
+	d := r.Define(toggle.Definition{Name: "Workers.Count", Type: toggle.IntFlag, Default: "4", Description: "Number of workers"})
+	a.Equal("workers.count", d.Name)
+
+	a.NotPanics(func() { r.Define(d) })
+	a.Panics(func() { r.Define(toggle.Definition{Name: "workers.count", Type: toggle.IntFlag, Default: "8"}) })
+	a.Panics(func() { r.Define(toggle.Definition{Name: "ratio", Type: toggle.FloatFlag, Default: "half"}) })
+	a.Panics(func() { r.Define(toggle.Definition{Name: "ratio", Type: "decimal"}) })
+	a.Panics(func() { r.Define(toggle.Definition{}) })
+
+	r.Define(toggle.Definition{Name: "checkout", Type: toggle.BoolFlag})
+
+	got, ok := r.Lookup("Workers_Count")
+	a.True(ok)
+	a.Equal(d, got)
+	_, ok = r.Lookup("missing")
+	a.False(ok)
+
+	a.Equal([]toggle.Definition{{Name: "checkout", Type: toggle.BoolFlag}, d}, r.Definitions())
+}
+
+func TestFlagType_Check(t *testing.T) {
+	tests := []struct {
+		typ     toggle.FlagType
+		raw     string
+		wantErr bool
+	}{
+		{typ: "", raw: "anything"},
+		{typ: toggle.StringFlag, raw: ""},
+		{typ: toggle.BoolFlag, raw: "Yes"},
+		{typ: toggle.BoolFlag, raw: "0"},
+		{typ: toggle.BoolFlag, raw: "maybe", wantErr: true},
+		{typ: toggle.IntFlag, raw: "-4"},
+		{typ: toggle.IntFlag, raw: "4.5", wantErr: true},
+		{typ: toggle.FloatFlag, raw: "4.5"},
+		{typ: toggle.FloatFlag, raw: "", wantErr: true},
+		{typ: toggle.DurationFlag, raw: "1m30s"},
+		{typ: toggle.DurationFlag, raw: "90", wantErr: true},
+		{typ: toggle.JSONFlag, raw: `{"a": [1]}`},
+		{typ: toggle.JSONFlag, raw: `{"a"`, wantErr: true},
+		{typ: "decimal", raw: "1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.typ)+" "+tt.raw, func(t *testing.T) {
+			err := tt.typ.Check(tt.raw)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestClient_definitions(t *testing.T) {
+	a := assert.New(t)
+
+	r := toggle.NewRegistry()
+	r.Define(toggle.Definition{Name: "workers", Type: toggle.IntFlag, Default: "4"})
+	r.Define(toggle.Definition{Name: "checkout", Type: toggle.BoolFlag, Default: "t"})
+	r.Define(toggle.Definition{Name: "theme", Description: "Untyped flag without a default"})
+
+	c := toggle.New("serv1", toggle.WithRegistry(r))
+
+	a.Equal(int64(4), c.GetInt("workers", 1))
+	a.True(c.Get("checkout"))
+	a.Equal("", c.GetRaw("theme"))
+
+	d := c.Evaluate("workers")
+	a.Equal(toggle.DefaultReason, d.Reason)
+	a.Equal(toggle.DefinitionSource, d.Source)
+	a.Equal("4", d.RawValue)
+
+	a.Equal(toggle.NotFoundReason, c.Evaluate("theme").Reason)
+
+	gated := toggle.NewFlag("checkout", "serv1", "f")
+	gated.Condition = condition(t, `userID == 1`)
+	c.SetFlags(toggle.NewFlag("workers", "serv1", "many"), gated)
+
+	d = c.Evaluate("workers")
+	a.Equal(toggle.DefaultReason, d.Reason, "values of the wrong type use the default")
+	a.Equal("4", d.RawValue)
+	a.True(c.Get("checkout"))
+	a.False(c.Get("checkout", toggle.ForInt("userID", 1)))
+
+	c.SetFlags(toggle.NewFlag("workers", "serv1", "8"))
+	a.Equal(int64(8), c.GetInt("workers", 1))
+	a.Equal(toggle.MatchedReason, c.Evaluate("workers").Reason)
+}
+
+func TestClient_Connect_definitions(t *testing.T) {
+	a := assert.New(t)
+
+	store := storage.NewMem()
+	ts := httptest.NewServer(api.Handler("/flags", store, messaging.NewNoop()))
+	defer ts.Close()
+
+	r := toggle.NewRegistry()
+	r.Define(toggle.Definition{Name: "workers", Type: toggle.IntFlag, Default: "4", Owner: "team1"})
+
+	c := toggle.New("serv1", toggle.WithRegistry(r))
+	c.ParseEnv([]string{"FEATURE_SERV1_CHECKOUT=t", "FEATURE__GLOBAL__" + toggle.ServerAddressFlag + "=" + ts.URL})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	c.Connect(ctx)
+	a.NoError(c.WaitReady(ctx))
+
+	defs, err := store.Definitions(ctx, "serv1")
+	a.NoError(err)
+	a.Equal([]toggle.Definition{{Name: "workers", ServiceName: "serv1", Type: toggle.IntFlag, Default: "4", Owner: "team1"}}, defs)
+}
@@ -0,0 +1,183 @@
+package toggle
+
+import "time"
+
+// Source describes where a stored flag was obtained from
+type Source string
+
+// Reason describes the outcome of a flag evaluation
+type Reason string
+
+const (
+	EnvSource   Source = "env"
+	SeedSource  Source = "seed"
+	PollSource  Source = "poll"
+	EventSource Source = "event"
+
+	SnapshotSource Source = "snapshot"
+	// ManualSource is given for flags set using the client methods
+	ManualSource Source = "manual"
+	// OverrideSource is given for flags overridden by the context
+	OverrideSource Source = "override"
+	// DefinitionSource is given for values obtained from the flag definition
+	DefinitionSource Source = "definition"
+)
+
+const (
+	// NotFoundReason is given when no flag with the name exists
+	NotFoundReason Reason = "NOT_FOUND"
+	// ServiceFilteredReason is given when the flag only exists in a scope that
+	// wasn't requested, such as a global flag without the Global option
+	ServiceFilteredReason Reason = "SERVICE_FILTERED"
+	// ConditionFailedReason is given when the flag conditions didn't match
+	ConditionFailedReason Reason = "CONDITION_FAILED"
+	// MatchedReason is given when a flag matched and none of its rules did
+	MatchedReason Reason = "MATCHED"
+	// RuleMatchedReason is given when a flag and one of its rules matched
+	RuleMatchedReason Reason = "RULE_MATCHED"
+	// InactiveReason is given when the flag is outside its activation window
+	InactiveReason Reason = "INACTIVE"
+	// OverriddenReason is given when the flag value was overridden by the
+	// context
+	OverriddenReason Reason = "OVERRIDDEN"
+	// DefaultReason is given when the declared default of the flag was used,
+	// as no flag matched or its value wasn't valid for the declared type
+	DefaultReason Reason = "DEFAULT"
+)
+
+// EvaluationDetail describes the outcome of a flag evaluation
+type EvaluationDetail struct {
+	Name     string
+	Value    bool
+	RawValue string
+
+	// Flag is the matched flag entry, or the last one whose condition
+	// failed. Its ServiceName is the matched scope, empty for global flags.
+	Flag   Flag
+	Source Source
+	Reason Reason
+
+	// Rule is the index of the matched flag rule, or -1
+	Rule int
+	// FailedField is the condition field which caused a mismatch, if any. It
+	// is shared with the stored flag and must not be modified.
+	FailedField *ConditionField
+}
+
+// Evaluate evaluates the flag and describes how its value was obtained
+func (c *Client) Evaluate(name string, opts ...Option) EvaluationDetail {
+	o := (getOptions{}).Apply(opts)
+
+	return c.evaluate(name, o)
+}
+
+// evaluate looks for the first flag whose condition matches, and resolves its
+// value using its rules. Service flags are considered before global ones,
+// which are only considered if requested.
+func (c *Client) evaluate(name string, o getOptions) EvaluationDetail {
+	d := c.evaluateIn(c.loadStore(), name, o)
+
+	if c.opts.evaluationListener != nil {
+		c.opts.evaluationListener(d)
+	}
+
+	return d
+}
+
+// evaluateIn evaluates the flag in the given store, which is either a
+// published one or the locked one
+func (c *Client) evaluateIn(store map[string][]entry, name string, o getOptions) EvaluationDetail {
+	name = NormalizeName(name)
+	d := c.evaluateStored(store[name], name, o)
+
+	if d.Reason == OverriddenReason {
+		return d
+	}
+
+	def, ok := c.opts.registry.Lookup(name)
+	if !ok || d.Reason.matched() && def.Type.Check(d.RawValue) == nil || def.Default == "" {
+		return d
+	}
+
+	d.RawValue, d.Value = def.Default, NewFlag(name, "", def.Default).Value
+	d.Source, d.Reason, d.Rule = DefinitionSource, DefaultReason, -1
+
+	return d
+}
+
+// evaluateStored evaluates the overrides and stored flags, without considering
+// the flag definition
+func (c *Client) evaluateStored(entries []entry, name string, o getOptions) EvaluationDetail {
+	d := EvaluationDetail{Name: name, Reason: NotFoundReason, Rule: -1}
+
+	if raw, ok := o.overrides[name]; ok {
+		// The stored flag is kept for its variants
+		d.Flag = NewFlag(name, c.name, raw)
+		if len(entries) > 0 {
+			d.Flag = entries[0].Flag
+		}
+		d.RawValue, d.Value = raw, NewFlag(name, "", raw).Value
+		d.Source, d.Reason = OverrideSource, OverriddenReason
+		return d
+	}
+
+	if len(entries) == 0 {
+		return d
+	}
+
+	values := c.values(o)
+
+	// The clock is only read for flags depending on the current time
+	var now time.Time
+	var timed bool
+
+	for _, serviceName := range []string{c.name, ""} {
+		if serviceName == "" && !o.global {
+			if d.Reason == NotFoundReason {
+				d.Reason = ServiceFilteredReason
+			}
+			break
+		}
+
+		for _, e := range entries {
+			if e.ServiceName != serviceName {
+				continue
+			}
+
+			if e.timed && !timed {
+				now, timed = c.opts.clock(), true
+				values = mergeValues(values, []ConditionValue{{Name: NowValue, Type: TimeType, Value: now}})
+			}
+
+			if !e.Active(now) {
+				d.Flag, d.Source = e.Flag, e.source
+				d.Reason, d.FailedField = InactiveReason, nil
+				continue
+			}
+
+			// The condition is only explained on a mismatch
+			if !e.match(values) {
+				_, field := e.explain(values)
+				d.Flag, d.Source = e.Flag, e.source
+				d.Reason, d.FailedField = ConditionFailedReason, field
+				continue
+			}
+
+			d.Flag, d.Source, d.FailedField = e.Flag, e.source, nil
+			d.RawValue, d.Value, d.Rule = e.resolve(values)
+			if d.Rule == -1 {
+				d.Reason = MatchedReason
+			} else {
+				d.Reason = RuleMatchedReason
+			}
+
+			return d
+		}
+	}
+
+	return d
+}
+
+func (r Reason) matched() bool {
+	return r == MatchedReason || r == RuleMatchedReason || r == OverriddenReason
+}
@@ -0,0 +1,106 @@
+package toggle
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_Evaluate(t *testing.T) {
+	expiredFlag := Flag{Name: "checkout", ServiceName: "serv1", RawValue: "t", Value: true, ActiveUntil: &windowEnd}
+	tenantField := ConditionField{ConditionValue: ConditionValue{Name: "tenant", Type: StringType, Value: "internal"}}
+
+	tests := []struct {
+		name    string
+		env     []string
+		entries []entry
+		opts    []Option
+		want    EvaluationDetail
+	}{
+		{name: "not found", want: EvaluationDetail{Name: "checkout", Reason: NotFoundReason, Rule: -1}},
+		{name: "env", env: []string{"FEATURE_SERV1_CHECKOUT=yes"}, want: EvaluationDetail{
+			Name: "checkout", Value: true, RawValue: "yes", Reason: MatchedReason, Rule: -1, Source: EnvSource,
+			Flag: Flag{Name: "checkout", ServiceName: "serv1", RawValue: "yes", Value: true},
+		}},
+		{name: "global not requested", env: []string{"FEATURE__GLOBAL__CHECKOUT=yes"}, want: EvaluationDetail{
+			Name: "checkout", Reason: ServiceFilteredReason, Rule: -1,
+		}},
+		{name: "global", env: []string{"FEATURE__GLOBAL__CHECKOUT=yes"}, opts: []Option{Global}, want: EvaluationDetail{
+			Name: "checkout", Value: true, RawValue: "yes", Reason: MatchedReason, Rule: -1, Source: EnvSource,
+			Flag: Flag{Name: "checkout", RawValue: "yes", Value: true},
+		}},
+		{name: "condition failed", entries: []entry{{Flag: gatedFlag, source: EventSource}}, opts: []Option{ForString("tenant", "beta")}, want: EvaluationDetail{
+			Name: "checkout", Reason: ConditionFailedReason, Rule: -1, Source: EventSource, Flag: gatedFlag, FailedField: &tenantField,
+		}},
+		{name: "condition failed falls through", entries: []entry{{Flag: gatedFlag, source: EventSource}, {Flag: globalRulesFlag, source: PollSource}}, opts: []Option{Global, ForString("tenant", "beta")}, want: EvaluationDetail{
+			Name: "checkout", Value: true, RawValue: "global beta", Reason: RuleMatchedReason, Rule: 0, Source: PollSource, Flag: globalRulesFlag,
+		}},
+		{name: "rule", entries: []entry{{Flag: rulesFlag, source: SeedSource}}, opts: []Option{ForInt("userID", 50)}, want: EvaluationDetail{
+			Name: "checkout", Value: true, RawValue: "late", Reason: RuleMatchedReason, Rule: 2, Source: SeedSource, Flag: rulesFlag,
+		}},
+		{name: "inactive", entries: []entry{{Flag: expiredFlag, source: PollSource}}, want: EvaluationDetail{
+			Name: "checkout", Reason: InactiveReason, Rule: -1, Source: PollSource, Flag: expiredFlag,
+		}},
+		{name: "inactive falls through", entries: []entry{{Flag: expiredFlag, source: PollSource}, {Flag: globalRulesFlag, source: PollSource}}, opts: []Option{Global}, want: EvaluationDetail{
+			Name: "checkout", Value: true, RawValue: "global", Reason: MatchedReason, Rule: -1, Source: PollSource, Flag: globalRulesFlag,
+		}},
+		{name: "rule fallthrough", entries: []entry{{Flag: rulesFlag, source: SeedSource}}, opts: []Option{ForInt("userID", 5)}, want: EvaluationDetail{
+			Name: "checkout", RawValue: "default", Reason: MatchedReason, Rule: -1, Source: SeedSource, Flag: rulesFlag,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New("serv1")
+			c.ParseEnv(tt.env)
+			c.modifyStore(func() {
+				for _, e := range tt.entries {
+					c.store[e.Name] = append(c.store[e.Name], newEntry(e.Flag, e.source))
+				}
+			})
+
+			assert.Equal(t, tt.want, c.Evaluate("Checkout", tt.opts...))
+		})
+	}
+}
+
+func TestCondition_explain(t *testing.T) {
+	userField := ConditionField{ConditionValue: ConditionValue{Name: "userID", Type: IntType, Value: int64(10)}, Op: LtOp}
+	tenantField := ConditionField{ConditionValue: ConditionValue{Name: "tenant", Type: StringType, Value: "beta"}}
+	planField := ConditionField{ConditionValue: ConditionValue{Name: "plan", Type: StringType, Value: "pro"}}
+
+	// (10 < userID || tenant == "beta") && plan == "pro"
+	cond := Condition{Conditions: []Condition{
+		{Op: OrOp, Fields: []ConditionField{userField, tenantField}},
+	}, Fields: []ConditionField{planField}}
+
+	tests := []struct {
+		name   string
+		values []ConditionValue
+		want   bool
+		field  *ConditionField
+	}{
+		{name: "no values", field: &userField},
+		{name: "or failed", values: []ConditionValue{
+			{Name: "userID", Type: IntType, Value: int64(5)},
+			{Name: "plan", Type: StringType, Value: "pro"},
+		}, field: &userField},
+		{name: "and failed", values: []ConditionValue{
+			{Name: "tenant", Type: StringType, Value: "beta"},
+			{Name: "plan", Type: StringType, Value: "free"},
+		}, field: &planField},
+		{name: "match", values: []ConditionValue{
+			{Name: "tenant", Type: StringType, Value: "beta"},
+			{Name: "plan", Type: StringType, Value: "pro"},
+		}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, field := cond.explain(tt.values)
+
+			a := assert.New(t)
+			a.Equal(tt.want, got)
+			a.Equal(cond.Match(tt.values), got)
+			a.Equal(tt.field, field)
+		})
+	}
+}
@@ -8,6 +8,10 @@ const (
 	SaveEvent   EventType = "save"
 	DeleteEvent EventType = "delete"
 	ErrorEvent  EventType = "error"
+
+	// ResyncEvent is sent when events might have been missed, and the flags
+	// have to be obtained from the server again
+	ResyncEvent EventType = "resync"
 )
 
 type Event struct {
@@ -0,0 +1,169 @@
+package toggle
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// State describes the connection state of a client
+type State string
+
+const (
+	// IdleState is the state of a client which isn't connecting to a server,
+	// either because Connect wasn't called or no server is configured
+	IdleState State = "idle"
+	// ConnectingState is the state of a client which hasn't obtained the
+	// server flags yet
+	ConnectingState State = "connecting"
+	// ConnectedState is the state of a client whose last server request
+	// succeeded
+	ConnectedState State = "connected"
+	// DisconnectedState is the state of a client whose last poll failed, or
+	// which gave up connecting
+	DisconnectedState State = "disconnected"
+	// ClosedState is the state of a client which was closed, or whose
+	// connection context was canceled
+	ClosedState State = "closed"
+)
+
+// ErrStopped is returned when waiting for a client which stopped connecting
+// before being ready
+var ErrStopped = errors.New("client stopped connecting before being ready")
+
+// Status describes the connection health of a client
+type Status struct {
+	State State
+	// LastSync is the time flags were last obtained from the server
+	LastSync time.Time
+	// LastError is the last error encountered while connecting
+	LastError error
+}
+
+// lifecycle holds the connection state of a client
+type lifecycle struct {
+	ready     chan struct{}
+	readyOnce sync.Once
+
+	stopped  chan struct{}
+	stopOnce sync.Once
+
+	mu     sync.Mutex
+	status Status
+	cancel context.CancelFunc
+	errC   chan error
+}
+
+func newLifecycle() *lifecycle {
+	return &lifecycle{
+		ready:   make(chan struct{}),
+		stopped: make(chan struct{}),
+		status:  Status{State: IdleState},
+	}
+}
+
+// Ready returns a channel which is closed once the client has obtained its
+// flags from the server or a snapshot, or immediately after connecting if no
+// server is configured
+func (c *Client) Ready() <-chan struct{} {
+	return c.lifecycle.ready
+}
+
+// WaitReady waits until the client is ready, the context is done or the client
+// stops connecting
+func (c *Client) WaitReady(ctx context.Context) error {
+	select {
+	case <-c.lifecycle.ready:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.lifecycle.stopped:
+		select {
+		case <-c.lifecycle.ready:
+			return nil
+		default:
+			return ErrStopped
+		}
+	}
+}
+
+// Status returns the connection status of the client
+func (c *Client) Status() Status {
+	c.lifecycle.mu.Lock()
+	defer c.lifecycle.mu.Unlock()
+
+	return c.lifecycle.status
+}
+
+// Close stops the connection started by Connect, waiting until polling stops
+// and the event bus receiver is released. Errors which haven't been read are
+// discarded. The flags remain available after closing.
+func (c *Client) Close() {
+	l := c.lifecycle
+
+	l.mu.Lock()
+	cancel, errC := l.cancel, l.errC
+	l.mu.Unlock()
+
+	if cancel == nil {
+		l.stop(ClosedState)
+		return
+	}
+
+	cancel()
+	<-l.stopped
+	l.setState(ClosedState)
+
+	for range errC {
+	}
+}
+
+// start prepares the lifecycle for a new connection
+func (l *lifecycle) start(cancel context.CancelFunc, errC chan error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.cancel, l.errC = cancel, errC
+}
+
+func (l *lifecycle) markReady() {
+	l.readyOnce.Do(func() {
+		close(l.ready)
+	})
+}
+
+// stop marks the connection as stopped with the given final state
+func (l *lifecycle) stop(state State) {
+	l.setState(state)
+	l.stopOnce.Do(func() {
+		close(l.stopped)
+	})
+}
+
+func (l *lifecycle) setState(state State) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.status.State = state
+}
+
+// synced records a successful server request
+func (l *lifecycle) synced() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.status.State = ConnectedState
+	l.status.LastSync = time.Now()
+}
+
+// failed records an error, changing the state of a connected client
+func (l *lifecycle) failed(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.status.LastError = err
+	if l.status.State == ConnectedState {
+		l.status.State = DisconnectedState
+	}
+}
@@ -0,0 +1,89 @@
+package toggle_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/globusdigital/feature-toggles/toggle"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_lifecycle(t *testing.T) {
+	var serverErr bool
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if serverErr {
+			http.Error(w, "error", 500)
+			return
+		}
+		b, _ := json.Marshal(initialData)
+		_, _ = w.Write(b)
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		name      string
+		noServer  bool
+		serverErr bool
+		backoff   toggle.Backoff
+		wantErr   error
+		state     toggle.State
+		synced    bool
+	}{
+		{name: "no server", noServer: true, state: toggle.IdleState},
+		{name: "connected", state: toggle.ConnectedState, synced: true},
+		{name: "gave up", serverErr: true, backoff: toggle.Backoff{Initial: time.Millisecond, MaxRetries: 2}, wantErr: toggle.ErrStopped, state: toggle.DisconnectedState},
+		{name: "connecting", serverErr: true, backoff: toggle.Backoff{Initial: time.Millisecond}, wantErr: context.DeadlineExceeded, state: toggle.ConnectingState},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := assert.New(t)
+			serverErr = tt.serverErr
+
+			c := toggle.New("serv1", toggle.WithBackoff(tt.backoff))
+			a.Equal(toggle.IdleState, c.Status().State)
+
+			env := seed1
+			if !tt.noServer {
+				env = append(env, "FEATURE__GLOBAL__"+toggle.ServerAddressFlag+"="+ts.URL)
+			}
+			c.ParseEnv(env)
+
+			// The errors are not read, which must not block the client
+			_ = c.Connect(context.Background())
+
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+
+			a.Equal(tt.wantErr, c.WaitReady(ctx))
+
+			status := c.Status()
+			a.Equal(tt.state, status.State)
+			a.Equal(tt.synced, !status.LastSync.IsZero())
+			a.Equal(tt.serverErr, status.LastError != nil)
+
+			if tt.wantErr == nil {
+				select {
+				case <-c.Ready():
+				default:
+					t.Errorf("Expected a ready client")
+				}
+			}
+
+			c.Close()
+			a.Equal(toggle.ClosedState, c.Status().State)
+			c.Close()
+		})
+	}
+}
+
+func TestClient_Close_unconnected(t *testing.T) {
+	c := toggle.New("serv1")
+	c.Close()
+
+	assert.Equal(t, toggle.ClosedState, c.Status().State)
+	assert.Equal(t, toggle.ErrStopped, c.WaitReady(context.Background()))
+}
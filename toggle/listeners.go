@@ -0,0 +1,114 @@
+package toggle
+
+import "reflect"
+
+// ChangeFunc is called with the previous and the current stored flag when it
+// changes. A zero flag denotes a missing one.
+type ChangeFunc func(old, new Flag)
+
+type listener struct {
+	name string
+	fn   ChangeFunc
+}
+
+// OnChange registers a function which is called when the stored service or
+// global flag with the given name changes due to a server seed, poll or event.
+// As the value of a flag may depend on the condition values of each call,
+// listeners are called for any change of the flag, and not only when a value
+// changes. Listeners aren't called when the current time enters or leaves a
+// flag activation window. The returned function unregisters the listener.
+//
+// Listeners are called synchronously after the update and should not block.
+func (c *Client) OnChange(name string, fn ChangeFunc) func() {
+	return c.addListener(NormalizeName(name), fn)
+}
+
+// OnAnyChange registers a function which is called when any stored service or
+// global flag changes. See OnChange.
+func (c *Client) OnAnyChange(fn ChangeFunc) func() {
+	return c.addListener("", fn)
+}
+
+func (c *Client) addListener(name string, fn ChangeFunc) func() {
+	c.lmu.Lock()
+	defer c.lmu.Unlock()
+
+	if c.listeners == nil {
+		c.listeners = map[int]listener{}
+	}
+
+	c.listenerID++
+	id := c.listenerID
+	c.listeners[id] = listener{name: name, fn: fn}
+
+	return func() {
+		c.lmu.Lock()
+		defer c.lmu.Unlock()
+
+		delete(c.listeners, id)
+	}
+}
+
+// modifyStore applies the store modification and notifies the listeners of
+// any changed flags
+func (c *Client) modifyStore(apply func()) {
+	c.lmu.Lock()
+	listeners := make([]listener, 0, len(c.listeners))
+	for _, l := range c.listeners {
+		listeners = append(listeners, l)
+	}
+	c.lmu.Unlock()
+
+	c.mu.Lock()
+	if len(listeners) == 0 {
+		apply()
+		c.publish()
+		c.mu.Unlock()
+		return
+	}
+
+	before := c.storedFlags()
+	apply()
+	c.publish()
+	after := c.storedFlags()
+	c.mu.Unlock()
+
+	for key, old := range before {
+		if updated := after[key]; !reflect.DeepEqual(old, updated) {
+			notify(listeners, key.name, old, updated)
+		}
+	}
+
+	for key, updated := range after {
+		if _, ok := before[key]; !ok {
+			notify(listeners, key.name, Flag{}, updated)
+		}
+	}
+}
+
+type flagKey struct {
+	name, serviceName string
+}
+
+// storedFlags returns the stored flags of the client service and the global
+// ones, while the caller holds the store lock
+func (c *Client) storedFlags() map[flagKey]Flag {
+	flags := make(map[flagKey]Flag, len(c.store))
+	for name, entries := range c.store {
+		for _, e := range entries {
+			if e.ServiceName == c.name || e.ServiceName == "" {
+				flags[flagKey{name, e.ServiceName}] = e.Flag
+			}
+		}
+	}
+
+	return flags
+}
+
+func notify(listeners []listener, name string, old, updated Flag) {
+	for _, l := range listeners {
+		if l.name == "" || l.name == name {
+			l.fn(old, updated)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package toggle
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type change struct {
+	old, new string
+}
+
+func TestClient_OnChange(t *testing.T) {
+	a := assert.New(t)
+
+	c := New("serv1")
+	c.ParseEnv([]string{"FEATURE_SERV1_WORKERS=4", "FEATURE__GLOBAL__CACHE=t"})
+
+	var workers, all []change
+	unsubscribe := c.OnChange("Workers", func(old, new Flag) {
+		workers = append(workers, change{old.RawValue, new.RawValue})
+	})
+	unsubscribeAll := c.OnAnyChange(func(old, new Flag) {
+		all = append(all, change{old.Name + "=" + old.RawValue, new.Name + "=" + new.RawValue})
+	})
+
+	// Seed response with an unchanged value
+	a.NoError(c.updateStore(strings.NewReader(`[{"name":"workers","service":"serv1","raw":"4"},{"name":"cache","raw":"t","value":true}]`), SeedSource))
+	a.Empty(workers)
+	a.Empty(all)
+
+	// Poll with a changed value
+	a.NoError(c.updateStore(strings.NewReader(`[{"name":"workers","service":"serv1","raw":"8"},{"name":"cache","raw":"t","value":true}]`), PollSource))
+	a.Equal([]change{{"4", "8"}}, workers)
+	a.Equal([]change{{"workers=4", "workers=8"}}, all)
+
+	// A changed condition is reported with an unchanged value
+	cond := Condition{Fields: []ConditionField{{ConditionValue: ConditionValue{Name: "userID", Type: IntType, Value: int64(10)}}}}
+	c.processEvent(Event{Type: SaveEvent, Flags: []Flag{{Name: "cache", RawValue: "t", Value: true, Expr: "userID == 10", Condition: cond}}})
+	a.Equal([]change{{"4", "8"}}, workers)
+	a.Equal([]change{{"workers=4", "workers=8"}, {"cache=t", "cache=t"}}, all)
+
+	// The value of a flag depending on call values changes
+	c.processEvent(Event{Type: SaveEvent, Flags: []Flag{{Name: "cache", RawValue: "f", Expr: "userID == 10", Condition: cond}}})
+	a.Equal([]change{{"workers=4", "workers=8"}, {"cache=t", "cache=t"}, {"cache=t", "cache=f"}}, all)
+
+	// Events for other services are ignored
+	c.processEvent(Event{Type: SaveEvent, Flags: []Flag{{Name: "workers", ServiceName: "serv2", RawValue: "16"}}})
+	a.Len(all, 3)
+
+	unsubscribeAll()
+
+	c.processEvent(Event{Type: DeleteEvent, Flags: []Flag{{Name: "workers", ServiceName: "serv1"}}})
+	a.Equal([]change{{"4", "8"}, {"8", ""}}, workers)
+	a.Len(all, 3)
+
+	unsubscribe()
+
+	c.processEvent(Event{Type: SaveEvent, Flags: []Flag{{Name: "workers", ServiceName: "serv1", RawValue: "2"}}})
+	a.Len(workers, 2)
+}
@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/globusdigital/feature-toggles/toggle"
+)
+
+// Header extracts the value of the header or metadata key as the named
+// condition value of the given type. Missing and unparsable values, such as
+// invalid semantic versions, are skipped. Times are RFC 3339 timestamps.
+func Header(key, name string, typ toggle.ValueType) Extractor {
+	return func(ctx context.Context, md Metadata) []toggle.ConditionValue {
+		raw := md.Get(key)
+		if raw == "" {
+			return nil
+		}
+
+		var value interface{}
+		var err error
+		switch typ {
+		case toggle.IntType:
+			value, err = strconv.ParseInt(raw, 10, 64)
+		case toggle.FloatType:
+			value, err = strconv.ParseFloat(raw, 64)
+		case toggle.BoolType:
+			value, err = strconv.ParseBool(raw)
+		case toggle.TimeType:
+			value, err = time.Parse(time.RFC3339Nano, raw)
+		default:
+			value = raw
+		}
+
+		v := toggle.ConditionValue{Name: name, Type: typ, Value: value}
+		if err != nil || v.Validate() != nil {
+			return nil
+		}
+
+		return []toggle.ConditionValue{v}
+	}
+}
+
+// ClaimsParser parses and verifies a bearer token, returning its claims
+type ClaimsParser func(token string) (map[string]interface{}, error)
+
+// JWTClaims extracts the given claims of the bearer token in the Authorization
+// header as condition values of the same name. The token is parsed and
+// verified by the given parser, and skipped if it is invalid. String, boolean
+// and numeric claims are supported, with integral numbers becoming integers.
+func JWTClaims(parse ClaimsParser, claims ...string) Extractor {
+	return func(ctx context.Context, md Metadata) []toggle.ConditionValue {
+		auth := md.Get("Authorization")
+		if len(auth) < 7 || !strings.EqualFold(auth[:7], "bearer ") {
+			return nil
+		}
+
+		parsed, err := parse(strings.TrimSpace(auth[7:]))
+		if err != nil {
+			return nil
+		}
+
+		var values []toggle.ConditionValue
+		for _, name := range claims {
+			var v toggle.ConditionValue
+			switch claim := parsed[name].(type) {
+			case string:
+				v = toggle.ConditionValue{Name: name, Type: toggle.StringType, Value: claim}
+			case bool:
+				v = toggle.ConditionValue{Name: name, Type: toggle.BoolType, Value: claim}
+			case float64:
+				if claim == math.Trunc(claim) && math.Abs(claim) < 1<<53 {
+					v = toggle.ConditionValue{Name: name, Type: toggle.IntType, Value: int64(claim)}
+				} else {
+					v = toggle.ConditionValue{Name: name, Type: toggle.FloatType, Value: claim}
+				}
+			default:
+				continue
+			}
+			values = append(values, v)
+		}
+
+		return values
+	}
+}
+
+// UnverifiedClaims is a ClaimsParser which decodes the claims of a JWT
+// without verifying its signature. It must only be used for tokens already
+// verified, such as by an API gateway or a preceding middleware.
+func UnverifiedClaims(token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil, err
+	}
+
+	var claims map[string]interface{}
+	if err := json.Unmarshal(b, &claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,55 @@
+// Package grpcmiddleware provides gRPC server interceptors which populate the
+// request contexts using a middleware.Populator. It is a separate module, so
+// that clients of the toggle package don't depend on gRPC.
+package grpcmiddleware
+
+import (
+	"context"
+
+	"github.com/globusdigital/feature-toggles/toggle/middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// Metadata adapts the gRPC metadata to the middleware.Metadata interface
+type Metadata metadata.MD
+
+// Get returns the first value of the key, which is case insensitive
+func (md Metadata) Get(key string) string {
+	if values := metadata.MD(md).Get(key); len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
+
+// UnaryServerInterceptor populates the contexts of unary calls from their
+// incoming metadata
+func UnaryServerInterceptor(p *middleware.Populator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(populate(ctx, p), req)
+	}
+}
+
+// StreamServerInterceptor populates the contexts of streams from their
+// incoming metadata
+func StreamServerInterceptor(p *middleware.Populator) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, serverStream{ServerStream: ss, ctx: populate(ss.Context(), p)})
+	}
+}
+
+func populate(ctx context.Context, p *middleware.Populator) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	return p.Populate(ctx, Metadata(md))
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s serverStream) Context() context.Context {
+	return s.ctx
+}
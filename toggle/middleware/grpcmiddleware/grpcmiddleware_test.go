@@ -0,0 +1,67 @@
+package grpcmiddleware_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/globusdigital/feature-toggles/toggle"
+	"github.com/globusdigital/feature-toggles/toggle/middleware"
+	"github.com/globusdigital/feature-toggles/toggle/middleware/grpcmiddleware"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+type stream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s stream) Context() context.Context {
+	return s.ctx
+}
+
+func TestInterceptors(t *testing.T) {
+	a := assert.New(t)
+
+	p := middleware.New(
+		middleware.WithExtractors(middleware.Header("x-tenant", "tenant", toggle.StringType)),
+		middleware.WithOverrides("checkout"),
+		middleware.WithOverrideAuthorizer(func(ctx context.Context, md middleware.Metadata) bool {
+			return md.Get("x-qa-session") != ""
+		}),
+	)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		"x-tenant", "t1",
+		"x-tenant", "t2",
+		"x-qa-session", "s1",
+		"X-Feature-Override", "checkout=t",
+	))
+	wantValues := []toggle.ConditionValue{{Name: "tenant", Type: toggle.StringType, Value: "t1"}}
+	wantOverrides := map[string]string{"checkout": "t"}
+
+	var got context.Context
+	_, err := grpcmiddleware.UnaryServerInterceptor(p)(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		got = ctx
+		return nil, nil
+	})
+	a.NoError(err)
+	a.Equal(wantValues, toggle.ValuesFromContext(got))
+	a.Equal(wantOverrides, toggle.OverridesFromContext(got))
+
+	got = nil
+	err = grpcmiddleware.StreamServerInterceptor(p)(nil, stream{ctx: ctx}, &grpc.StreamServerInfo{}, func(srv interface{}, ss grpc.ServerStream) error {
+		got = ss.Context()
+		return nil
+	})
+	a.NoError(err)
+	a.Equal(wantValues, toggle.ValuesFromContext(got))
+	a.Equal(wantOverrides, toggle.OverridesFromContext(got))
+
+	_, err = grpcmiddleware.UnaryServerInterceptor(p)(context.Background(), nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		got = ctx
+		return nil, nil
+	})
+	a.NoError(err)
+	a.Empty(toggle.ValuesFromContext(got))
+}
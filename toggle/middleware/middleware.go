@@ -0,0 +1,141 @@
+// Package middleware populates request contexts with the condition values and
+// flag overrides used by the toggle client context getters, such as GetCtx.
+//
+// The values are extracted from http request headers, or from gRPC metadata
+// using the interceptors of the grpcmiddleware module.
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/globusdigital/feature-toggles/toggle"
+)
+
+// DefaultOverrideHeader is the default header holding flag overrides
+const DefaultOverrideHeader = "X-Feature-Override"
+
+// Metadata provides the request headers or metadata by key. It is implemented
+// by http.Header.
+type Metadata interface {
+	Get(key string) string
+}
+
+// Extractor extracts condition values from the request metadata
+type Extractor func(ctx context.Context, md Metadata) []toggle.ConditionValue
+
+// Populator populates request contexts with the extracted condition values and
+// the allowed flag overrides
+type Populator struct {
+	extractors []Extractor
+
+	overrideHeader string
+	allowed        map[string]bool
+	authorize      func(ctx context.Context, md Metadata) bool
+}
+
+// Option configures a Populator
+type Option func(p *Populator)
+
+// New creates a populator with the given options
+func New(opts ...Option) *Populator {
+	p := &Populator{overrideHeader: DefaultOverrideHeader}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// WithExtractors adds extractors of condition values. Values extracted later
+// override earlier ones with the same name.
+func WithExtractors(extractors ...Extractor) Option {
+	return func(p *Populator) {
+		p.extractors = append(p.extractors, extractors...)
+	}
+}
+
+// WithOverrides enables flag overrides for the allowed flag names, given as a
+// comma separated list of name=value pairs in the override header, such as
+// "X-Feature-Override: checkout=t, button=blue". Overrides are disabled by
+// default, and only honoured for requests authorized by the function set with
+// WithOverrideAuthorizer.
+func WithOverrides(allowed ...string) Option {
+	return func(p *Populator) {
+		if p.allowed == nil {
+			p.allowed = map[string]bool{}
+		}
+		for _, name := range allowed {
+			p.allowed[toggle.NormalizeName(name)] = true
+		}
+	}
+}
+
+// WithOverrideHeader sets the header holding the flag overrides. Defaults to
+// DefaultOverrideHeader
+func WithOverrideHeader(header string) Option {
+	return func(p *Populator) {
+		p.overrideHeader = header
+	}
+}
+
+// WithOverrideAuthorizer sets a function which decides whether the overrides
+// of a request are honoured, such as only for QA sessions. No requests are
+// authorized by default.
+func WithOverrideAuthorizer(fn func(ctx context.Context, md Metadata) bool) Option {
+	return func(p *Populator) {
+		p.authorize = fn
+	}
+}
+
+// Populate returns a copy of the context with the condition values and flag
+// overrides extracted from the request metadata
+func (p *Populator) Populate(ctx context.Context, md Metadata) context.Context {
+	var values []toggle.ConditionValue
+	for _, extract := range p.extractors {
+		values = append(values, extract(ctx, md)...)
+	}
+	if len(values) > 0 {
+		ctx = toggle.WithValues(ctx, values...)
+	}
+
+	if overrides := p.overrides(ctx, md); len(overrides) > 0 {
+		ctx = toggle.WithOverrides(ctx, overrides)
+	}
+
+	return ctx
+}
+
+// Handler is a http middleware populating the request contexts
+func (p *Populator) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(p.Populate(r.Context(), r.Header)))
+	})
+}
+
+func (p *Populator) overrides(ctx context.Context, md Metadata) map[string]string {
+	if len(p.allowed) == 0 {
+		return nil
+	}
+
+	header := md.Get(p.overrideHeader)
+	if header == "" || p.authorize == nil || !p.authorize(ctx, md) {
+		return nil
+	}
+
+	overrides := map[string]string{}
+	for _, pair := range strings.Split(header, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		name := toggle.NormalizeName(strings.TrimSpace(parts[0]))
+		if p.allowed[name] {
+			overrides[name] = strings.TrimSpace(parts[1])
+		}
+	}
+
+	return overrides
+}
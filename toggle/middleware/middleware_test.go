@@ -0,0 +1,157 @@
+package middleware_test
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/globusdigital/feature-toggles/toggle"
+	"github.com/globusdigital/feature-toggles/toggle/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func token(claims string) string {
+	return "Bearer e30." + base64.RawURLEncoding.EncodeToString([]byte(claims)) + ".sig"
+}
+
+func qaSession(ctx context.Context, md middleware.Metadata) bool {
+	return md.Get("X-QA-Session") != ""
+}
+
+func TestPopulator_Populate(t *testing.T) {
+	tests := []struct {
+		name          string
+		opts          []middleware.Option
+		header        http.Header
+		wantValues    []toggle.ConditionValue
+		wantOverrides map[string]string
+	}{
+		{name: "no options", header: http.Header{"X-Tenant": {"t1"}, "X-Feature-Override": {"checkout=t"}}},
+		{
+			name: "headers",
+			opts: []middleware.Option{middleware.WithExtractors(
+				middleware.Header("X-Tenant", "tenant", toggle.StringType),
+				middleware.Header("X-User-Id", "userID", toggle.IntType),
+				middleware.Header("X-Beta", "beta", toggle.BoolType),
+				middleware.Header("X-Ratio", "ratio", toggle.FloatType),
+				middleware.Header("X-Missing", "missing", toggle.StringType),
+				middleware.Header("X-App-Version", "appVersion", toggle.SemverType),
+				middleware.Header("X-Client-Version", "clientVersion", toggle.SemverType),
+				middleware.Header("X-Request-Time", "requestTime", toggle.TimeType),
+			)},
+			header: http.Header{"X-Tenant": {"t1"}, "X-User-Id": {"42"}, "X-Beta": {"yes"}, "X-Ratio": {"0.5"}, "X-App-Version": {"4.12.0"}, "X-Client-Version": {"latest"}, "X-Request-Time": {"2021-03-01T09:00:00Z"}},
+			wantValues: []toggle.ConditionValue{
+				{Name: "tenant", Type: toggle.StringType, Value: "t1"},
+				{Name: "userID", Type: toggle.IntType, Value: int64(42)},
+				{Name: "ratio", Type: toggle.FloatType, Value: 0.5},
+				{Name: "appVersion", Type: toggle.SemverType, Value: "4.12.0"},
+				{Name: "requestTime", Type: toggle.TimeType, Value: time.Date(2021, 3, 1, 9, 0, 0, 0, time.UTC)},
+			},
+		},
+		{
+			name: "jwt claims",
+			opts: []middleware.Option{middleware.WithExtractors(
+				middleware.JWTClaims(middleware.UnverifiedClaims, "sub", "plan", "age", "score", "admin", "missing"),
+			)},
+			header: http.Header{"Authorization": {token(`{"sub":"u1","age":30,"score":1.5,"admin":true,"plan":{"a":1}}`)}},
+			wantValues: []toggle.ConditionValue{
+				{Name: "sub", Type: toggle.StringType, Value: "u1"},
+				{Name: "age", Type: toggle.IntType, Value: int64(30)},
+				{Name: "score", Type: toggle.FloatType, Value: 1.5},
+				{Name: "admin", Type: toggle.BoolType, Value: true},
+			},
+		},
+		{
+			name: "invalid jwt",
+			opts: []middleware.Option{middleware.WithExtractors(
+				middleware.JWTClaims(func(string) (map[string]interface{}, error) { return nil, errors.New("invalid") }, "sub"),
+			)},
+			header: http.Header{"Authorization": {token(`{"sub":"u1"}`)}},
+		},
+		{
+			name:          "allowed overrides",
+			opts:          []middleware.Option{middleware.WithOverrides("Checkout", "button"), middleware.WithOverrideAuthorizer(qaSession)},
+			header:        http.Header{"X-Qa-Session": {"s1"}, "X-Feature-Override": {"checkout=t, button = red ,payments=t,invalid"}},
+			wantOverrides: map[string]string{"checkout": "t", "button": "red"},
+		},
+		{
+			name:          "custom override header",
+			opts:          []middleware.Option{middleware.WithOverrides("checkout"), middleware.WithOverrideHeader("X-QA"), middleware.WithOverrideAuthorizer(qaSession)},
+			header:        http.Header{"X-Qa-Session": {"s1"}, "X-Qa": {"checkout=t"}, "X-Feature-Override": {"checkout=f"}},
+			wantOverrides: map[string]string{"checkout": "t"},
+		},
+		{
+			name:   "unauthorized overrides",
+			opts:   []middleware.Option{middleware.WithOverrides("checkout"), middleware.WithOverrideAuthorizer(qaSession)},
+			header: http.Header{"X-Feature-Override": {"checkout=t"}},
+		},
+		{
+			name:   "overrides without authorizer",
+			opts:   []middleware.Option{middleware.WithOverrides("checkout")},
+			header: http.Header{"X-Qa-Session": {"s1"}, "X-Feature-Override": {"checkout=t"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := assert.New(t)
+
+			var ctx context.Context
+			h := middleware.New(tt.opts...).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ctx = r.Context()
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header = tt.header
+			h.ServeHTTP(httptest.NewRecorder(), r)
+
+			a.Equal(tt.wantValues, toggle.ValuesFromContext(ctx))
+			a.Equal(tt.wantOverrides, toggle.OverridesFromContext(ctx))
+		})
+	}
+}
+
+func TestPopulator_Handler(t *testing.T) {
+	a := assert.New(t)
+
+	c := toggle.New("serv1")
+	c.SetFlags(toggle.Flag{Name: "checkout", ServiceName: "serv1", RawValue: "t", Value: true, Expr: `tenant == "t1"`,
+		Condition: toggle.Condition{Fields: []toggle.ConditionField{
+			{ConditionValue: toggle.ConditionValue{Name: "tenant", Type: toggle.StringType, Value: "t1"}},
+		}},
+	})
+
+	p := middleware.New(
+		middleware.WithExtractors(middleware.Header("X-Tenant", "tenant", toggle.StringType)),
+		middleware.WithOverrides("checkout"),
+		middleware.WithOverrideAuthorizer(qaSession),
+	)
+	srv := httptest.NewServer(p.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c.GetCtx(r.Context(), "checkout") {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})))
+	defer srv.Close()
+
+	get := func(header http.Header) int {
+		req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+		a.NoError(err)
+		req.Header = header
+		resp, err := http.DefaultClient.Do(req)
+		a.NoError(err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	a.Equal(http.StatusNotFound, get(http.Header{}))
+	a.Equal(http.StatusOK, get(http.Header{"X-Tenant": {"t1"}}))
+	a.Equal(http.StatusNotFound, get(http.Header{"X-Tenant": {"t2"}}))
+	a.Equal(http.StatusNotFound, get(http.Header{"X-Tenant": {"t2"}, "X-Feature-Override": {"checkout=t"}}))
+	a.Equal(http.StatusOK, get(http.Header{"X-Tenant": {"t2"}, "X-Qa-Session": {"s1"}, "X-Feature-Override": {"checkout=t"}}))
+	a.Equal(http.StatusNotFound, get(http.Header{"X-Tenant": {"t1"}, "X-Qa-Session": {"s1"}, "X-Feature-Override": {"checkout=f"}}))
+}
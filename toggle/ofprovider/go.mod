@@ -0,0 +1,19 @@
+module github.com/globusdigital/feature-toggles/toggle/ofprovider
+
+go 1.25.0
+
+require (
+	github.com/globusdigital/feature-toggles v0.0.0-00010101000000-000000000000
+	github.com/open-feature/go-sdk v1.14.1
+	github.com/stretchr/testify v1.8.4
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/go-logr/logr v1.4.2 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/exp v0.0.0-20240506185415-9bf2ced13842 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/globusdigital/feature-toggles => ../..
@@ -0,0 +1,344 @@
+// Package ofprovider adapts the toggle client to the OpenFeature provider
+// interface. It is a separate module, so that clients of the toggle package
+// don't depend on the OpenFeature SDK.
+package ofprovider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/globusdigital/feature-toggles/toggle"
+	"github.com/open-feature/go-sdk/openfeature"
+)
+
+// Name is the provider name reported in its metadata
+const Name = "feature-toggles"
+
+const eventBufferSize = 16
+
+// defaultInitTimeout bounds how long Init waits for the client by default
+const defaultInitTimeout = 10 * time.Second
+
+var (
+	_ openfeature.FeatureProvider = (*Provider)(nil)
+	_ openfeature.StateHandler    = (*Provider)(nil)
+	_ openfeature.EventHandler    = (*Provider)(nil)
+)
+
+// Provider resolves OpenFeature flags using a toggle client. The evaluation
+// context attributes are passed to the client as condition values, along
+// with any values and overrides carried by the go context.
+type Provider struct {
+	client *toggle.Client
+	opts   []toggle.Option
+
+	targetingKey string
+	initTimeout  time.Duration
+
+	mu          sync.Mutex
+	events      chan openfeature.Event
+	unsubscribe func()
+	cancel      context.CancelFunc
+}
+
+// Option configures a Provider
+type Option func(p *Provider)
+
+// WithOptions sets options which are used for every flag evaluation, such as
+// toggle.Global. The evaluation context attributes take precedence over them.
+func WithOptions(opts ...toggle.Option) Option {
+	return func(p *Provider) {
+		p.opts = append(p.opts, opts...)
+	}
+}
+
+// WithTargetingKey sets the condition value name of the evaluation context
+// targeting key, such as "userID". Defaults to "targetingKey".
+func WithTargetingKey(name string) Option {
+	return func(p *Provider) {
+		p.targetingKey = name
+	}
+}
+
+// WithInitTimeout sets how long Init waits for the client to be ready.
+// Defaults to 10 seconds. Zero waits until the client is ready or the provider
+// is shut down.
+func WithInitTimeout(d time.Duration) Option {
+	return func(p *Provider) {
+		p.initTimeout = d
+	}
+}
+
+// New creates a provider for the client. The client is expected to be
+// connected by the caller.
+func New(c *toggle.Client, opts ...Option) *Provider {
+	p := &Provider{
+		client:       c,
+		targetingKey: openfeature.TargetingKey,
+		initTimeout:  defaultInitTimeout,
+		events:       make(chan openfeature.Event, eventBufferSize),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// Metadata returns the provider metadata
+func (p *Provider) Metadata() openfeature.Metadata {
+	return openfeature.Metadata{Name: Name}
+}
+
+// Hooks returns the provider hooks, of which there are none
+func (p *Provider) Hooks() []openfeature.Hook {
+	return nil
+}
+
+// Init waits until the client is ready and starts emitting configuration
+// change events. It returns an error if the client isn't ready within the
+// init timeout, in which case a ready event is emitted once it is, or if the
+// provider is shut down before the client is ready.
+func (p *Provider) Init(evaluationContext openfeature.EvaluationContext) error {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	p.mu.Lock()
+	if p.cancel != nil {
+		p.cancel()
+	}
+	p.cancel = cancel
+	if p.unsubscribe == nil {
+		p.unsubscribe = p.client.OnAnyChange(p.flagChanged)
+	}
+	p.mu.Unlock()
+
+	waitCtx := ctx
+	if p.initTimeout > 0 {
+		var cancelWait context.CancelFunc
+		waitCtx, cancelWait = context.WithTimeout(ctx, p.initTimeout)
+		defer cancelWait()
+	}
+
+	if err := p.client.WaitReady(waitCtx); err != nil {
+		if ctx.Err() == nil {
+			go p.ready(ctx)
+		}
+		return fmt.Errorf("waiting for the toggle client: %v", err)
+	}
+
+	return nil
+}
+
+// ready emits a ready event once the client is ready, unless the provider is
+// shut down before
+func (p *Provider) ready(ctx context.Context) {
+	if err := p.client.WaitReady(ctx); err != nil {
+		return
+	}
+
+	p.emit(openfeature.Event{
+		ProviderName:         Name,
+		EventType:            openfeature.ProviderReady,
+		ProviderEventDetails: openfeature.ProviderEventDetails{Message: "toggle client ready"},
+	})
+}
+
+// Shutdown stops emitting events. The client itself is not closed.
+func (p *Provider) Shutdown() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.cancel != nil {
+		p.cancel()
+		p.cancel = nil
+	}
+	if p.unsubscribe != nil {
+		p.unsubscribe()
+		p.unsubscribe = nil
+	}
+}
+
+// EventChannel returns the channel of provider events
+func (p *Provider) EventChannel() <-chan openfeature.Event {
+	return p.events
+}
+
+// flagChanged emits a configuration change event without blocking the client
+// store updates. Events are dropped if the channel buffer is full.
+func (p *Provider) flagChanged(old, new toggle.Flag) {
+	name := new.Name
+	if name == "" {
+		name = old.Name
+	}
+
+	ev := openfeature.Event{
+		ProviderName: Name,
+		EventType:    openfeature.ProviderConfigChange,
+		ProviderEventDetails: openfeature.ProviderEventDetails{
+			Message:     "flag " + name + " changed",
+			FlagChanges: []string{name},
+		},
+	}
+
+	p.emit(ev)
+}
+
+// emit sends the event, unless the event buffer is full
+func (p *Provider) emit(ev openfeature.Event) {
+	select {
+	case p.events <- ev:
+	default:
+	}
+}
+
+// BooleanEvaluation resolves a boolean flag. Raw values which aren't one of
+// 1, y, yes, t, true, 0, n, no, f or false are a type mismatch.
+func (p *Provider) BooleanEvaluation(ctx context.Context, flag string, defaultValue bool, evalCtx openfeature.FlattenedContext) openfeature.BoolResolutionDetail {
+	d, detail, ok := p.evaluate(ctx, flag, evalCtx)
+	if !ok {
+		return openfeature.BoolResolutionDetail{Value: defaultValue, ProviderResolutionDetail: detail}
+	}
+
+	switch strings.ToLower(d.RawValue) {
+	case "1", "y", "yes", "t", "true":
+		return openfeature.BoolResolutionDetail{Value: true, ProviderResolutionDetail: detail}
+	case "0", "n", "no", "f", "false":
+		return openfeature.BoolResolutionDetail{Value: false, ProviderResolutionDetail: detail}
+	}
+
+	return openfeature.BoolResolutionDetail{Value: defaultValue, ProviderResolutionDetail: typeMismatch(flag, d.RawValue, "bool")}
+}
+
+// StringEvaluation resolves a string flag as its raw value
+func (p *Provider) StringEvaluation(ctx context.Context, flag string, defaultValue string, evalCtx openfeature.FlattenedContext) openfeature.StringResolutionDetail {
+	d, detail, ok := p.evaluate(ctx, flag, evalCtx)
+	if !ok {
+		return openfeature.StringResolutionDetail{Value: defaultValue, ProviderResolutionDetail: detail}
+	}
+
+	return openfeature.StringResolutionDetail{Value: d.RawValue, ProviderResolutionDetail: detail}
+}
+
+// FloatEvaluation resolves a float flag
+func (p *Provider) FloatEvaluation(ctx context.Context, flag string, defaultValue float64, evalCtx openfeature.FlattenedContext) openfeature.FloatResolutionDetail {
+	d, detail, ok := p.evaluate(ctx, flag, evalCtx)
+	if !ok {
+		return openfeature.FloatResolutionDetail{Value: defaultValue, ProviderResolutionDetail: detail}
+	}
+
+	v, err := strconv.ParseFloat(d.RawValue, 64)
+	if err != nil {
+		return openfeature.FloatResolutionDetail{Value: defaultValue, ProviderResolutionDetail: typeMismatch(flag, d.RawValue, "float")}
+	}
+
+	return openfeature.FloatResolutionDetail{Value: v, ProviderResolutionDetail: detail}
+}
+
+// IntEvaluation resolves an integer flag
+func (p *Provider) IntEvaluation(ctx context.Context, flag string, defaultValue int64, evalCtx openfeature.FlattenedContext) openfeature.IntResolutionDetail {
+	d, detail, ok := p.evaluate(ctx, flag, evalCtx)
+	if !ok {
+		return openfeature.IntResolutionDetail{Value: defaultValue, ProviderResolutionDetail: detail}
+	}
+
+	v, err := strconv.ParseInt(d.RawValue, 10, 64)
+	if err != nil {
+		return openfeature.IntResolutionDetail{Value: defaultValue, ProviderResolutionDetail: typeMismatch(flag, d.RawValue, "int")}
+	}
+
+	return openfeature.IntResolutionDetail{Value: v, ProviderResolutionDetail: detail}
+}
+
+// ObjectEvaluation resolves an object flag by decoding its raw JSON value
+func (p *Provider) ObjectEvaluation(ctx context.Context, flag string, defaultValue interface{}, evalCtx openfeature.FlattenedContext) openfeature.InterfaceResolutionDetail {
+	d, detail, ok := p.evaluate(ctx, flag, evalCtx)
+	if !ok {
+		return openfeature.InterfaceResolutionDetail{Value: defaultValue, ProviderResolutionDetail: detail}
+	}
+
+	var v interface{}
+	if err := json.Unmarshal([]byte(d.RawValue), &v); err != nil {
+		return openfeature.InterfaceResolutionDetail{Value: defaultValue, ProviderResolutionDetail: openfeature.ProviderResolutionDetail{
+			ResolutionError: openfeature.NewParseErrorResolutionError(fmt.Sprintf("parsing flag %s value %q as json: %v", flag, d.RawValue, err)),
+			Reason:          openfeature.ErrorReason,
+		}}
+	}
+
+	return openfeature.InterfaceResolutionDetail{Value: v, ProviderResolutionDetail: detail}
+}
+
+// evaluate evaluates the flag and maps the outcome to a resolution detail. The
+// flag value should be used if ok is true, including declared defaults.
+func (p *Provider) evaluate(ctx context.Context, flag string, evalCtx openfeature.FlattenedContext) (d toggle.EvaluationDetail, detail openfeature.ProviderResolutionDetail, ok bool) {
+	opts := append(append([]toggle.Option(nil), p.opts...), p.contextOptions(evalCtx)...)
+	d = p.client.EvaluateCtx(ctx, flag, opts...)
+
+	detail = openfeature.ProviderResolutionDetail{
+		FlagMetadata: openfeature.FlagMetadata{"source": string(d.Source), "reason": string(d.Reason)},
+	}
+	switch d.Reason {
+	case toggle.NotFoundReason, toggle.ServiceFilteredReason:
+		detail.Reason = openfeature.ErrorReason
+		detail.ResolutionError = openfeature.NewFlagNotFoundResolutionError("flag " + flag + " not found")
+	case toggle.ConditionFailedReason, toggle.DefaultReason:
+		detail.Reason = openfeature.DefaultReason
+	case toggle.InactiveReason:
+		detail.Reason = openfeature.DisabledReason
+	case toggle.RuleMatchedReason:
+		detail.Reason = openfeature.TargetingMatchReason
+	case toggle.MatchedReason:
+		detail.Reason = openfeature.StaticReason
+		if d.Flag.Expr != "" {
+			detail.Reason = openfeature.TargetingMatchReason
+		}
+	default:
+		detail.Reason = openfeature.Reason(d.Reason)
+	}
+
+	return d, detail, d.Reason != toggle.ConditionFailedReason && d.Reason != toggle.InactiveReason &&
+		detail.Reason != openfeature.ErrorReason
+}
+
+// contextOptions converts the evaluation context attributes to condition
+// values. Attributes of unsupported types are skipped.
+func (p *Provider) contextOptions(evalCtx openfeature.FlattenedContext) []toggle.Option {
+	var opts []toggle.Option
+	for name, v := range evalCtx {
+		if name == openfeature.TargetingKey {
+			name = p.targetingKey
+		}
+
+		switch v := v.(type) {
+		case string:
+			opts = append(opts, toggle.ForString(name, v))
+		case bool:
+			opts = append(opts, toggle.ForBool(name, v))
+		case int:
+			opts = append(opts, toggle.ForInt(name, int64(v)))
+		case int32:
+			opts = append(opts, toggle.ForInt(name, int64(v)))
+		case int64:
+			opts = append(opts, toggle.ForInt(name, v))
+		case float32:
+			opts = append(opts, toggle.ForFloat(name, float64(v)))
+		case float64:
+			opts = append(opts, toggle.ForFloat(name, v))
+		case time.Time:
+			opts = append(opts, toggle.ForTime(name, v))
+		}
+	}
+
+	return opts
+}
+
+func typeMismatch(flag, raw, typ string) openfeature.ProviderResolutionDetail {
+	return openfeature.ProviderResolutionDetail{
+		ResolutionError: openfeature.NewTypeMismatchResolutionError(fmt.Sprintf("flag %s value %q is not a %s", flag, raw, typ)),
+		Reason:          openfeature.ErrorReason,
+	}
+}
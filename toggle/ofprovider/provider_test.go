@@ -0,0 +1,201 @@
+package ofprovider_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/globusdigital/feature-toggles/toggle"
+	"github.com/globusdigital/feature-toggles/toggle/ofprovider"
+	"github.com/open-feature/go-sdk/openfeature"
+	"github.com/stretchr/testify/assert"
+)
+
+func condition(t *testing.T, expr string) toggle.Condition {
+	cond, err := toggle.ParseCondition(strings.NewReader(expr))
+	if err != nil {
+		t.Fatalf("Error parsing condition %q: %v", expr, err)
+	}
+
+	return cond
+}
+
+func newClient(t *testing.T) *toggle.Client {
+	c := toggle.New("serv1")
+	gated := toggle.NewFlag("gated", "serv1", "t")
+	gated.Expr, gated.Condition = `userID == 1`, condition(t, `userID == 1`)
+	ruled := toggle.NewFlag("ruled", "serv1", "small")
+	ruled.Rules = []toggle.Rule{{Condition: condition(t, `tenant == "t1"`), RawValue: "large"}}
+	c.SetFlags(
+		toggle.NewFlag("enabled", "serv1", "yes"),
+		toggle.NewFlag("workers", "serv1", "4"),
+		toggle.NewFlag("ratio", "serv1", "0.5"),
+		toggle.NewFlag("config", "serv1", `{"a":1}`),
+		toggle.NewFlag("cache", "", "t"),
+		gated, ruled,
+	)
+
+	return c
+}
+
+func TestProvider_evaluation(t *testing.T) {
+	a := assert.New(t)
+
+	p := ofprovider.New(newClient(t), ofprovider.WithTargetingKey("userID"))
+	ctx := context.Background()
+
+	b := p.BooleanEvaluation(ctx, "enabled", false, nil)
+	a.True(b.Value)
+	a.Equal(openfeature.StaticReason, b.Reason)
+	a.Empty(b.ResolutionDetail().ErrorCode)
+
+	b = p.BooleanEvaluation(ctx, "workers", true, nil)
+	a.True(b.Value)
+	a.Equal(openfeature.ErrorReason, b.Reason)
+	a.Equal(openfeature.TypeMismatchCode, b.ResolutionDetail().ErrorCode)
+
+	b = p.BooleanEvaluation(ctx, "missing", true, nil)
+	a.True(b.Value)
+	a.Equal(openfeature.FlagNotFoundCode, b.ResolutionDetail().ErrorCode)
+
+	b = p.BooleanEvaluation(ctx, "cache", false, nil)
+	a.Equal(openfeature.FlagNotFoundCode, b.ResolutionDetail().ErrorCode)
+
+	b = p.BooleanEvaluation(ctx, "gated", false, openfeature.FlattenedContext{openfeature.TargetingKey: 2})
+	a.False(b.Value)
+	a.Equal(openfeature.DefaultReason, b.Reason)
+
+	b = p.BooleanEvaluation(ctx, "gated", false, openfeature.FlattenedContext{openfeature.TargetingKey: 1})
+	a.True(b.Value)
+	a.Equal(openfeature.TargetingMatchReason, b.Reason)
+
+	s := p.StringEvaluation(ctx, "ruled", "", openfeature.FlattenedContext{"tenant": "t1"})
+	a.Equal("large", s.Value)
+	a.Equal(openfeature.TargetingMatchReason, s.Reason)
+	a.Equal("small", p.StringEvaluation(ctx, "ruled", "", openfeature.FlattenedContext{"tenant": "t2"}).Value)
+
+	i := p.IntEvaluation(ctx, "workers", 1, nil)
+	a.Equal(int64(4), i.Value)
+	i = p.IntEvaluation(ctx, "ratio", 1, nil)
+	a.Equal(int64(1), i.Value)
+	a.Equal(openfeature.TypeMismatchCode, i.ResolutionDetail().ErrorCode)
+
+	a.Equal(0.5, p.FloatEvaluation(ctx, "ratio", 1, nil).Value)
+
+	o := p.ObjectEvaluation(ctx, "config", nil, nil)
+	a.Equal(map[string]interface{}{"a": float64(1)}, o.Value)
+	o = p.ObjectEvaluation(ctx, "workers", "def", nil)
+	a.Equal(4.0, o.Value)
+	o = p.ObjectEvaluation(ctx, "ruled", "def", nil)
+	a.Equal("def", o.Value)
+	a.Equal(openfeature.ParseErrorCode, o.ResolutionDetail().ErrorCode)
+}
+
+func TestProvider_options(t *testing.T) {
+	a := assert.New(t)
+
+	p := ofprovider.New(newClient(t), ofprovider.WithOptions(toggle.Global))
+
+	a.True(p.BooleanEvaluation(context.Background(), "cache", false, nil).Value)
+
+	ctx := toggle.WithOverrides(context.Background(), map[string]string{"enabled": "f"})
+	b := p.BooleanEvaluation(ctx, "enabled", true, nil)
+	a.False(b.Value)
+	a.Equal(openfeature.Reason(toggle.OverriddenReason), b.Reason)
+
+	ctx = toggle.WithValues(context.Background(), toggle.ConditionValue{Name: "userID", Value: int64(1)})
+	a.True(p.BooleanEvaluation(ctx, "gated", false, nil).Value)
+
+	p = ofprovider.New(newClient(t), ofprovider.WithOptions(toggle.ForString("tenant", "t2")))
+	a.Equal("small", p.StringEvaluation(context.Background(), "ruled", "", nil).Value)
+	a.Equal("large", p.StringEvaluation(context.Background(), "ruled", "", openfeature.FlattenedContext{"tenant": "t1"}).Value, "the evaluation context takes precedence")
+}
+
+func TestProvider_definitions(t *testing.T) {
+	a := assert.New(t)
+
+	r := toggle.NewRegistry()
+	r.Define(toggle.Definition{Name: "workers", Type: toggle.IntFlag, Default: "4"})
+
+	p := ofprovider.New(toggle.New("serv1", toggle.WithRegistry(r)))
+
+	i := p.IntEvaluation(context.Background(), "workers", 1, nil)
+	a.Equal(int64(4), i.Value)
+	a.Equal(openfeature.DefaultReason, i.Reason)
+	a.Empty(i.ResolutionDetail().ErrorCode)
+}
+
+func TestProvider_events(t *testing.T) {
+	a := assert.New(t)
+
+	c := newClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c.Connect(ctx)
+
+	p := ofprovider.New(c)
+	a.Equal(ofprovider.Name, p.Metadata().Name)
+	a.NoError(p.Init(openfeature.EvaluationContext{}))
+
+	c.SetFlags(toggle.NewFlag("workers", "serv1", "8"))
+
+	select {
+	case ev := <-p.EventChannel():
+		a.Equal(openfeature.ProviderConfigChange, ev.EventType)
+		a.Equal([]string{"workers"}, ev.FlagChanges)
+	case <-time.After(time.Second):
+		t.Fatal("No configuration change event")
+	}
+
+	p.Shutdown()
+	c.SetFlags(toggle.NewFlag("workers", "serv1", "16"))
+
+	select {
+	case ev := <-p.EventChannel():
+		t.Fatalf("Unexpected event after shutdown: %v", ev)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestProvider_Init_shutdown(t *testing.T) {
+	a := assert.New(t)
+
+	p := ofprovider.New(toggle.New("serv1"))
+
+	errC := make(chan error)
+	go func() {
+		errC <- p.Init(openfeature.EvaluationContext{})
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	p.Shutdown()
+
+	select {
+	case err := <-errC:
+		a.Error(err)
+	case <-time.After(time.Second):
+		t.Fatal("Init didn't return after shutdown")
+	}
+}
+
+func TestProvider_Init_timeout(t *testing.T) {
+	a := assert.New(t)
+
+	c := toggle.New("serv1")
+	p := ofprovider.New(c, ofprovider.WithInitTimeout(50*time.Millisecond))
+	defer p.Shutdown()
+
+	a.Error(p.Init(openfeature.EvaluationContext{}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c.Connect(ctx)
+
+	select {
+	case ev := <-p.EventChannel():
+		a.Equal(openfeature.ProviderReady, ev.EventType)
+	case <-time.After(time.Second):
+		t.Fatal("No ready event")
+	}
+}
@@ -22,25 +22,44 @@ type logger interface {
 }
 
 type getOptions struct {
-	global bool
-	values []ConditionValue
+	global    bool
+	values    []ConditionValue
+	ctxValues []ConditionValue
+	overrides map[string]string
 }
 
 type clientOptions struct {
 	values         []ConditionValue
 	eventBus       EventBus
+	serverEvents   bool
 	updateDuration time.Duration
+	backoff        Backoff
 	httpClient     *http.Client
 	log            logger
 	path           string
+
+	snapshotPath   string
+	snapshotMaxAge time.Duration
+
+	evaluationListener func(EvaluationDetail)
+
+	registry *Registry
+	clock    func() time.Time
 }
 
 func (o getOptions) Apply(opts []Option) getOptions {
+	// The options are applied to a copy, so that calls without any options
+	// don't move the receiver to the heap
+	if len(opts) == 0 {
+		return o
+	}
+
+	applied := o
 	for _, opt := range opts {
-		opt(&o)
+		opt(&applied)
 	}
 
-	return o
+	return applied
 }
 
 // ForInt sets an integer value when querying a flag constraint
@@ -71,6 +90,21 @@ func ForString(name string, value string) Option {
 	}
 }
 
+// ForSemver sets a semantic version value, such as "4.12.0", when querying a
+// flag constraint
+func ForSemver(name string, version string) Option {
+	return func(o *getOptions) {
+		o.values = append(o.values, ConditionValue{Name: name, Value: version, Type: SemverType})
+	}
+}
+
+// ForTime sets a time value when querying a flag constraint
+func ForTime(name string, value time.Time) Option {
+	return func(o *getOptions) {
+		o.values = append(o.values, ConditionValue{Name: name, Value: value, Type: TimeType})
+	}
+}
+
 func (o clientOptions) Apply(opts []ClientOption) clientOptions {
 	for _, opt := range opts {
 		opt(&o)
@@ -81,25 +115,65 @@ func (o clientOptions) Apply(opts []ClientOption) clientOptions {
 
 // For sets the global condition values that can be used for any flag
 // constraints. The service name is an always present global constraint value.
+// Context and call values with the same name take precedence over them.
 func For(values ...ConditionValue) ClientOption {
-	for i := range values {
-		switch v := values[i].Value.(type) {
+	values = inferTypes(values)
+
+	return func(o *clientOptions) {
+		o.values = mergeValues(o.values, values)
+	}
+}
+
+// inferTypes returns a copy of the values, with their types set according to
+// the Go types of their values. Strings keep the SemverType.
+func inferTypes(values []ConditionValue) []ConditionValue {
+	typed := make([]ConditionValue, len(values))
+	for i, v := range values {
+		switch val := v.Value.(type) {
 		case int64:
-			values[i].Type = IntType
+			v.Type = IntType
 		case float64:
-			values[i].Type = FloatType
+			v.Type = FloatType
 		case bool:
-			values[i].Type = BoolType
+			v.Type = BoolType
+		case time.Time:
+			v.Type = TimeType
 		case string:
-			values[i].Type = StringType
+			if v.Type != SemverType {
+				v.Type = StringType
+			}
 		default:
-			panic(fmt.Sprintf("Unsupported type: %T", v))
+			panic(fmt.Sprintf("Unsupported type: %T", val))
 		}
+		typed[i] = v
 	}
 
-	return func(o *clientOptions) {
-		o.values = append(o.values, values...)
+	return typed
+}
+
+// mergeValues returns the base values with the given ones appended, replacing
+// any base value with the same name. The base is returned as is, without
+// copying, when there are no values to merge.
+func mergeValues(base []ConditionValue, values []ConditionValue) []ConditionValue {
+	if len(values) == 0 {
+		return base
 	}
+
+	merged := make([]ConditionValue, len(base), len(base)+len(values))
+	copy(merged, base)
+
+outer:
+	for _, v := range values {
+		for i := range merged {
+			if merged[i].Name == v.Name {
+				merged[i] = v
+				continue outer
+			}
+		}
+		merged = append(merged, v)
+	}
+
+	return merged
 }
 
 // WithEventBus sets the event bus to be used for listening for events on flag updates
@@ -109,6 +183,14 @@ func WithEventBus(bus EventBus) ClientOption {
 	}
 }
 
+// WithServerEvents sets the client to receive events on flag updates from the
+// stream endpoint of the flags server, instead of using a separate event bus
+func WithServerEvents() ClientOption {
+	return func(o *clientOptions) {
+		o.serverEvents = true
+	}
+}
+
 // WithPollingUpdateDuration sets the duration between poll iterations.
 // Defaults to 30m
 func WithPollingUpdateDuration(d time.Duration) ClientOption {
@@ -117,6 +199,14 @@ func WithPollingUpdateDuration(d time.Duration) ClientOption {
 	}
 }
 
+// WithBackoff sets the backoff policy for retrying failed seed and poll
+// requests. Defaults to DefaultBackoff
+func WithBackoff(b Backoff) ClientOption {
+	return func(o *clientOptions) {
+		o.backoff = b
+	}
+}
+
 // WithHttpClient sets the http client used for contacting the flags server.
 // Defaults to http.DefaultClient
 func WithHttpClient(c *http.Client) ClientOption {
@@ -140,3 +230,50 @@ func WithAPIPath(p string) ClientOption {
 		o.path = p
 	}
 }
+
+// WithSnapshotFile sets the path of a file in which the flags obtained from the
+// server are persisted. When connecting, the snapshot is loaded before the
+// server is contacted, so that the last known flags are available while the
+// server is unreachable. Snapshot flags take precedence over environment flags
+// with the same name and service, and are replaced by the server flags once
+// they are obtained.
+func WithSnapshotFile(path string) ClientOption {
+	return func(o *clientOptions) {
+		o.snapshotPath = path
+	}
+}
+
+// WithSnapshotMaxAge sets the maximum age of a snapshot file for it to be
+// loaded. A non-positive duration disables the check. Defaults to 24h
+func WithSnapshotMaxAge(d time.Duration) ClientOption {
+	return func(o *clientOptions) {
+		o.snapshotMaxAge = d
+	}
+}
+
+// WithEvaluationListener sets a function which is called with the details of
+// every flag evaluation made by the client getters. It is called synchronously
+// and should not block.
+func WithEvaluationListener(fn func(EvaluationDetail)) ClientOption {
+	return func(o *clientOptions) {
+		o.evaluationListener = fn
+	}
+}
+
+// WithRegistry sets the registry of the flag definitions, whose defaults and
+// types are used when evaluating flags, and which are sent to the server when
+// connecting. Defaults to the DefaultRegistry.
+func WithRegistry(r *Registry) ClientOption {
+	return func(o *clientOptions) {
+		o.registry = r
+	}
+}
+
+// WithClock sets the function returning the current time, which is used for
+// the now condition value and the flag activation windows. Defaults to
+// time.Now
+func WithClock(clock func() time.Time) ClientOption {
+	return func(o *clientOptions) {
+		o.clock = clock
+	}
+}
@@ -3,6 +3,7 @@ package toggle
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"strconv"
@@ -18,18 +19,44 @@ const (
 	floatLit  // float
 	boolLit   // boolean
 	stringLit // string
+	semverLit // semantic version
+	timeLit   // time
+
+	andOp  // && operator
+	orOp   // || operator
+	eqOp   // == operator
+	neOp   // != operator
+	ltOp   // < operator
+	gtOp   // > operator
+	leOp   // <= operator
+	geOp   // >= operator
+	inOp   // in operator
+	notOp  // not operator
+	textOp // text operator
+
+	openParen    // (
+	closeParen   // )
+	comma        // ,
+	openBracket  // [
+	closeBracket // ]
+)
 
-	andOp // && operator
-	orOp  // || operator
-	eqOp  // == operator
-	neOp  // != operator
-	ltOp  // < operator
-	gtOp  // > operator
+const (
+	bucketFunc = "bucket"
 
-	openParen  // (
-	closeParen // )
+	inKeyword  = "in"
+	notKeyword = "not"
 )
 
+// textOps are the keywords of the text operators
+var textOps = map[string]FieldOp{
+	"matches":    MatchesOp,
+	"like":       LikeOp,
+	"startsWith": StartsWithOp,
+	"endsWith":   EndsWithOp,
+	"contains":   ContainsOp,
+}
+
 type token struct {
 	kind   kind
 	pos    int
@@ -60,7 +87,7 @@ func ParseCondition(r io.Reader) (Condition, error) {
 		condition.Op = AndOp
 	}
 
-	return condition, nil
+	return condition.withPatterns(), nil
 }
 
 func parseCondition(tokens []*token, toplevel bool, openP int) (Condition, int, error) {
@@ -71,7 +98,7 @@ func parseCondition(tokens []*token, toplevel bool, openP int) (Condition, int,
 		t := tokens[i]
 
 		switch t.kind {
-		case ident, eqOp, neOp, ltOp, gtOp, intLit, floatLit, boolLit, stringLit:
+		case ident, eqOp, neOp, ltOp, gtOp, leOp, geOp, intLit, floatLit, boolLit, stringLit, semverLit, timeLit:
 			f, pos, err := parseField(tokens[i:])
 			if err != nil {
 				return c, i, err
@@ -137,6 +164,24 @@ func parseCondition(tokens []*token, toplevel bool, openP int) (Condition, int,
 
 			c.Conditions = append(c.Conditions, condition)
 			i += pos + 1
+		case notOp:
+			// not ( condition )
+			if i+1 >= len(tokens) || tokens[i+1].kind != openParen {
+				return c, i, fmt.Errorf("expected parenthesis after (%s)", t)
+			}
+
+			condition, pos, err := parseCondition(tokens[i+2:], true, openP+1)
+			if err != nil {
+				return c, i, err
+			}
+
+			if condition.Op == invalidConditionalOp {
+				condition.Op = AndOp
+			}
+			condition.Not = true
+
+			c.Conditions = append(c.Conditions, condition)
+			i += pos + 2
 
 		case closeParen:
 			if openP < 1 {
@@ -172,8 +217,20 @@ LOOP:
 				f.Name == "" && f.Value != nil && f.Op == invalidFieldOp {
 				return f, i, fmt.Errorf("unexpected token (%s)", t)
 			}
+
+			if i+1 < len(tokens) && tokens[i+1].kind == openParen {
+				name, bucket, pos, err := parseBucket(tokens[i:])
+				if err != nil {
+					return f, i, err
+				}
+
+				f.Name, f.Bucket = name, bucket
+				i += pos
+				continue
+			}
+
 			f.Name = string(t.val)
-		case eqOp, neOp, ltOp, gtOp:
+		case eqOp, neOp, ltOp, gtOp, leOp, geOp:
 			// op .
 			if f.Op != invalidFieldOp ||
 				// .
@@ -183,59 +240,87 @@ LOOP:
 				return f, i, fmt.Errorf("unexpected token (%s)", t)
 			}
 
+			// The operator is reversed when the value is the first token
+			reversed := f.Name == ""
+
 			switch t.kind {
 			case eqOp:
 				f.Op = EqOp
 			case neOp:
 				f.Op = NeOp
 			case ltOp:
-				// Value is the first token
-				if f.Name == "" {
+				f.Op = LtOp
+				if reversed {
 					f.Op = GtOp
-				} else {
-					f.Op = LtOp
 				}
 			case gtOp:
-				// Value is the first token
-				if f.Name == "" {
+				f.Op = GtOp
+				if reversed {
 					f.Op = LtOp
-				} else {
-					f.Op = GtOp
+				}
+			case leOp:
+				f.Op = LeOp
+				if reversed {
+					f.Op = GeOp
+				}
+			case geOp:
+				f.Op = GeOp
+				if reversed {
+					f.Op = LeOp
 				}
 			}
-		case intLit, floatLit, boolLit, stringLit:
+		case intLit, floatLit, boolLit, stringLit, semverLit, timeLit:
 			// value .
 			if f.Value != nil ||
 				// name .
-				f.Value == nil && f.Name != "" && f.Op == invalidFieldOp {
+				f.Value == nil && f.Name != "" && f.Op == invalidFieldOp ||
+				// name in .
+				f.Op.list() ||
+				// name contains .
+				f.Op.textual() && t.kind != stringLit {
 				return f, i, fmt.Errorf("unexpected token (%s)", t)
 			}
 
 			var err error
-			switch t.kind {
-			case stringLit:
-				f.Value = string(t.val)
-				f.Type = StringType
-			case intLit:
-				f.Value, err = strconv.ParseInt(string(t.val), 10, 64)
-				f.Type = IntType
-			case floatLit:
-				f.Value, err = strconv.ParseFloat(string(t.val), 10)
-				f.Type = FloatType
-			case boolLit:
-				f.Type = BoolType
-				if bytes.Equal(t.val, []byte("true")) {
-					f.Value = true
-				} else if bytes.Equal(t.val, []byte("false")) {
-					f.Value = false
-				} else {
-					err = fmt.Errorf("invalid boolean value: %s", string(t.val))
+			f.Value, f.Type, err = parseLiteral(t)
+			if err != nil {
+				return f, i, err
+			}
+		case textOp:
+			// name .
+			if f.Name == "" || f.Op != invalidFieldOp || f.Value != nil {
+				return f, i, fmt.Errorf("unexpected token (%s)", t)
+			}
+
+			f.Op = textOps[string(t.val)]
+		case inOp, notOp:
+			// name .
+			if f.Name == "" || f.Op != invalidFieldOp || f.Value != nil {
+				return f, i, fmt.Errorf("unexpected token (%s)", t)
+			}
+
+			f.Op = InOp
+			if t.kind == notOp {
+				// name not in
+				if i+1 >= len(tokens) || tokens[i+1].kind != inOp {
+					return f, i, fmt.Errorf("expected in after (%s)", t)
 				}
+				f.Op = NotInOp
+				i++
+			}
+		case openBracket:
+			// name in .
+			if !f.Op.list() || f.Value != nil {
+				return f, i, fmt.Errorf("unexpected token (%s)", t)
 			}
 
+			list, typ, pos, err := parseList(tokens[i:])
 			if err != nil {
-				return f, i, fmt.Errorf("unexpected value for token (%s): %v", t, err)
+				return f, i, err
 			}
+
+			f.Value, f.Type = list, typ
+			i += pos
 		}
 	}
 
@@ -246,6 +331,120 @@ LOOP:
 	return f, i, nil
 }
 
+// parseLiteral returns the value of a literal token, along with its type
+func parseLiteral(t *token) (interface{}, ValueType, error) {
+	var v interface{}
+	var typ ValueType
+	var err error
+
+	switch t.kind {
+	case stringLit:
+		v, typ = string(t.val), StringType
+	case semverLit:
+		v, typ = string(t.val), SemverType
+		if _, ok := parseSemver(string(t.val)); !ok {
+			err = errors.New("invalid semantic version")
+		}
+	case timeLit:
+		v, err = parseTime(string(t.val))
+		typ = TimeType
+	case intLit:
+		v, err = strconv.ParseInt(string(t.val), 10, 64)
+		typ = IntType
+	case floatLit:
+		v, err = strconv.ParseFloat(string(t.val), 10)
+		typ = FloatType
+	case boolLit:
+		typ = BoolType
+		if bytes.Equal(t.val, []byte("true")) {
+			v = true
+		} else if bytes.Equal(t.val, []byte("false")) {
+			v = false
+		} else {
+			err = fmt.Errorf("invalid boolean value: %s", string(t.val))
+		}
+	default:
+		err = errors.New("not a literal")
+	}
+
+	if err != nil {
+		return nil, typ, fmt.Errorf("unexpected value for token (%s): %v", t, err)
+	}
+
+	return v, typ, nil
+}
+
+// parseList parses a non-empty [literal, ...] list of literals of the same
+// type, and returns it as a slice of their underlying type
+func parseList(tokens []*token) (interface{}, ValueType, int, error) {
+	var elems []interface{}
+	var typ ValueType
+
+	// [ literal [, literal]... ]
+	i := 1
+	for {
+		if i >= len(tokens) {
+			return nil, typ, i, fmt.Errorf("unterminated list after (%s)", tokens[i-1])
+		}
+
+		v, t, err := parseLiteral(tokens[i])
+		if err != nil {
+			return nil, typ, i, fmt.Errorf("expected list value after (%s)", tokens[i-1])
+		}
+		if len(elems) > 0 && t != typ {
+			return nil, typ, i, fmt.Errorf("mixed list value types (%s)", tokens[i])
+		}
+		elems, typ = append(elems, v), t
+		i++
+
+		if i >= len(tokens) {
+			return nil, typ, i, fmt.Errorf("unterminated list after (%s)", tokens[i-1])
+		}
+
+		switch tokens[i].kind {
+		case comma:
+			i++
+		case closeBracket:
+			return typedList(typ, elems), typ, i, nil
+		default:
+			return nil, typ, i, fmt.Errorf("unexpected token (%s)", tokens[i])
+		}
+	}
+}
+
+// parseBucket parses a bucket(name[, "seed"]) function call
+func parseBucket(tokens []*token) (string, *Bucket, int, error) {
+	if string(tokens[0].val) != bucketFunc {
+		return "", nil, 0, fmt.Errorf("unknown function (%s)", tokens[0])
+	}
+
+	var name string
+	bucket := &Bucket{}
+
+	// bucket ( name [, seed] )
+	i := 2
+	if i >= len(tokens) || tokens[i].kind != ident {
+		return "", nil, i, fmt.Errorf("expected bucket value name after (%s)", tokens[i-1])
+	}
+	name = string(tokens[i].val)
+	i++
+
+	if i < len(tokens) && tokens[i].kind == comma {
+		i++
+		if i >= len(tokens) || tokens[i].kind != stringLit {
+			return "", nil, i, fmt.Errorf("expected bucket seed string after (%s)", tokens[i-1])
+		}
+		bucket.Seed = string(tokens[i].val)
+		i++
+	}
+
+	if i >= len(tokens) || tokens[i].kind != closeParen {
+		return "", nil, i, fmt.Errorf("unterminated bucket call after (%s)", tokens[i-1])
+	}
+
+	return name, bucket, i, nil
+}
+
 func lexer(r io.Reader) ([]*token, error) {
 	br := bufio.NewReader(r)
 
@@ -265,11 +464,7 @@ func lexer(r io.Reader) ([]*token, error) {
 		switch {
 		case unicode.IsSpace(r):
 			if t != nil {
-				if t.kind == ident {
-					if bytes.Equal(t.val, []byte("true")) || bytes.Equal(t.val, []byte("false")) {
-						t.kind = boolLit
-					}
-				}
+				endToken(t)
 
 				if t.kind == stringLit {
 					t.val, escapeNext = addRuneToString(t.val, r, escapeNext)
@@ -278,7 +473,10 @@ func lexer(r io.Reader) ([]*token, error) {
 				}
 			}
 		case r == '(':
-			if t == nil {
+			if t == nil || t.kind == ident || isNot(t) {
+				// An identifier directly followed by a parenthesis is a function
+				// call, unless it is the not keyword
+				endToken(t)
 				t = &token{kind: openParen, pos: curLen}
 			} else if t.kind == stringLit {
 				t.val, escapeNext = addRuneToString(t.val, r, escapeNext)
@@ -286,13 +484,41 @@ func lexer(r io.Reader) ([]*token, error) {
 				return tokens, fmt.Errorf("invalid character %v at position %d", string(r), curLen)
 			}
 		case r == ')':
-			if t == nil {
+			if t == nil || isValueToken(t) {
+				endToken(t)
 				t = &token{kind: closeParen, pos: curLen}
 			} else if t.kind == stringLit {
 				t.val, escapeNext = addRuneToString(t.val, r, escapeNext)
 			} else {
 				return tokens, fmt.Errorf("invalid character %v at position %d", string(r), curLen)
 			}
+		case r == ',':
+			if t == nil || isValueToken(t) {
+				endToken(t)
+				t = &token{kind: comma, pos: curLen}
+			} else if t.kind == stringLit {
+				t.val, escapeNext = addRuneToString(t.val, r, escapeNext)
+			} else {
+				return tokens, fmt.Errorf("invalid character %v at position %d", string(r), curLen)
+			}
+		case r == '[':
+			if t == nil || t.kind == ident {
+				endToken(t)
+				t = &token{kind: openBracket, pos: curLen}
+			} else if t.kind == stringLit {
+				t.val, escapeNext = addRuneToString(t.val, r, escapeNext)
+			} else {
+				return tokens, fmt.Errorf("invalid character %v at position %d", string(r), curLen)
+			}
+		case r == ']':
+			if t == nil || isValueToken(t) {
+				endToken(t)
+				t = &token{kind: closeBracket, pos: curLen}
+			} else if t.kind == stringLit {
+				t.val, escapeNext = addRuneToString(t.val, r, escapeNext)
+			} else {
+				return tokens, fmt.Errorf("invalid character %v at position %d", string(r), curLen)
+			}
 		case r == '<':
 			if t == nil {
 				t = &token{kind: ltOp, pos: curLen}
@@ -338,7 +564,14 @@ func lexer(r io.Reader) ([]*token, error) {
 				return tokens, fmt.Errorf("invalid character %v at position %d", string(r), curLen)
 			}
 		case r == '=':
-			if t == nil {
+			if prev := lastToken(tokens); t == nil && prev != nil && prev.pos == curLen-1 && (prev.kind == ltOp || prev.kind == gtOp) {
+				// <= and >= operators
+				if prev.kind == ltOp {
+					prev.kind = leOp
+				} else {
+					prev.kind = geOp
+				}
+			} else if t == nil {
 				t = &token{kind: eqOp, pos: curLen, val: []byte(string(r))}
 			} else if t.kind == stringLit {
 				t.val, escapeNext = addRuneToString(t.val, r, escapeNext)
@@ -382,8 +615,14 @@ func lexer(r io.Reader) ([]*token, error) {
 			switch t.kind {
 			case stringLit:
 				t.val, escapeNext = addRuneToString(t.val, r, escapeNext)
-			case ident:
+			case ident, semverLit:
 				t.val = append(t.val, []byte(string(r))...)
+			case timeLit:
+				// The time and UTC designators
+				if r != 'T' && r != 'Z' {
+					return tokens, fmt.Errorf("invalid character %v at position %d", string(r), curLen)
+				}
+				t.val = append(t.val, byte(r))
 			default:
 				return tokens, fmt.Errorf("invalid character %v at position %d", string(r), curLen)
 			}
@@ -395,7 +634,7 @@ func lexer(r io.Reader) ([]*token, error) {
 			switch t.kind {
 			case stringLit:
 				t.val, escapeNext = addRuneToString(t.val, r, escapeNext)
-			case ident, intLit, floatLit:
+			case ident, intLit, floatLit, semverLit, timeLit:
 				t.val = append(t.val, []byte(string(r))...)
 			default:
 				return tokens, fmt.Errorf("invalid character %v at position %d", string(r), curLen)
@@ -409,11 +648,28 @@ func lexer(r io.Reader) ([]*token, error) {
 			case intLit:
 				t.kind = floatLit
 				t.val = append(t.val, []byte(string(r))...)
+			case floatLit, semverLit:
+				// A second dot makes a version, such as 1.2.3
+				t.kind = semverLit
+				t.val = append(t.val, []byte(string(r))...)
+			case timeLit:
+				// Fractional seconds
+				t.val = append(t.val, byte(r))
 			case stringLit:
 				t.val, escapeNext = addRuneToString(t.val, r, escapeNext)
 			default:
 				return tokens, fmt.Errorf("invalid character %v at position %d", string(r), curLen)
 			}
+		case (r == '-' || r == '+') && t != nil && t.kind == semverLit:
+			// Version pre-release and build metadata
+			t.val = append(t.val, byte(r))
+		case r == '-' && t != nil && t.kind == intLit:
+			// A dash after a number makes a date, such as 2021-03-01
+			t.kind = timeLit
+			t.val = append(t.val, byte(r))
+		case (r == '-' || r == '+' || r == ':') && t != nil && t.kind == timeLit:
+			// Date and time separators, and time zone offsets
+			t.val = append(t.val, byte(r))
 		default:
 			if t == nil || t.kind != stringLit {
 				return tokens, fmt.Errorf("invalid character %v at position %d", string(r), curLen)
@@ -426,7 +682,7 @@ func lexer(r io.Reader) ([]*token, error) {
 		}
 		if t != nil {
 			switch t.kind {
-			case openParen, closeParen, ltOp, gtOp:
+			case openParen, closeParen, comma, openBracket, closeBracket, ltOp, gtOp:
 				t = nil
 			case andOp, orOp, eqOp, neOp:
 				if len(t.val) == 2 {
@@ -436,13 +692,55 @@ func lexer(r io.Reader) ([]*token, error) {
 		}
 		curLen += l
 	}
+	endToken(t)
+
+	return tokens, nil
+}
+
+// isValueToken checks whether the token is an identifier or a non-string
+// literal, which are terminated by any non-value character
+func isValueToken(t *token) bool {
+	switch t.kind {
+	case ident, intLit, floatLit, boolLit, semverLit, timeLit:
+		return true
+	}
+	return false
+}
+
+// isNot checks whether the token is an unterminated != operator, which is the
+// ! operator
+func isNot(t *token) bool {
+	return t.kind == neOp && len(t.val) == 1
+}
+
+func lastToken(tokens []*token) *token {
+	if len(tokens) == 0 {
+		return nil
+	}
+	return tokens[len(tokens)-1]
+}
+
+// endToken finalizes the kind of a token once its last character was read
+func endToken(t *token) {
+	if t != nil && isNot(t) {
+		t.kind, t.val = notOp, []byte(notKeyword)
+		return
+	}
+
 	if t != nil && t.kind == ident {
-		if bytes.Equal(t.val, []byte("true")) || bytes.Equal(t.val, []byte("false")) {
+		switch string(t.val) {
+		case "true", "false":
 			t.kind = boolLit
+		case inKeyword:
+			t.kind = inOp
+		case notKeyword:
+			t.kind = notOp
+		default:
+			if _, ok := textOps[string(t.val)]; ok {
+				t.kind = textOp
+			}
 		}
 	}
-
-	return tokens, nil
 }
 
 func addRuneToString(buf []byte, r rune, escapeNext bool) ([]byte, bool) {
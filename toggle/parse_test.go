@@ -1,8 +1,10 @@
 package toggle
 
 import (
+	"regexp"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -79,9 +81,100 @@ func Test_lexer(t *testing.T) {
 			{kind: eqOp, pos: 79, val: []byte("==")},
 			{kind: stringLit, pos: 82, val: []byte(`tes"t'er`), opened: '"'},
 		}},
-		{name: "invalid float", in: "14.1.2", wantErr: true},
+		{name: "invalid float", in: "14.1a", wantErr: true},
+		{name: "semver", in: "v >= 4.12.0-beta.1+build-5 && v < 5.0.0", want: []*token{
+			{kind: ident, pos: 0, val: []byte("v")},
+			{kind: geOp, pos: 2},
+			{kind: semverLit, pos: 5, val: []byte("4.12.0-beta.1+build-5")},
+			{kind: andOp, pos: 27, val: []byte("&&")},
+			{kind: ident, pos: 30, val: []byte("v")},
+			{kind: ltOp, pos: 32},
+			{kind: semverLit, pos: 34, val: []byte("5.0.0")},
+		}},
+		{name: "invalid semver", in: "4.12.0-beta!", wantErr: true},
+		{name: "time", in: "now >= 2021-03-01T09:00:00.5+02:00 && now < 2021-04-01", want: []*token{
+			{kind: ident, pos: 0, val: []byte("now")},
+			{kind: geOp, pos: 4},
+			{kind: timeLit, pos: 7, val: []byte("2021-03-01T09:00:00.5+02:00")},
+			{kind: andOp, pos: 35, val: []byte("&&")},
+			{kind: ident, pos: 38, val: []byte("now")},
+			{kind: ltOp, pos: 42},
+			{kind: timeLit, pos: 44, val: []byte("2021-04-01")},
+		}},
+		{name: "invalid time", in: "2021-03-01X", wantErr: true},
 		{name: "invalid char", in: "@", wantErr: true},
 		{name: ">", in: ">", want: []*token{{kind: gtOp}}},
+		{name: "bucket", in: `bucket(userID, "seed") < 25`, want: []*token{
+			{kind: ident, pos: 0, val: []byte("bucket")},
+			{kind: openParen, pos: 6},
+			{kind: ident, pos: 7, val: []byte("userID")},
+			{kind: comma, pos: 13},
+			{kind: stringLit, pos: 15, val: []byte("seed"), opened: '"'},
+			{kind: closeParen, pos: 21},
+			{kind: ltOp, pos: 23},
+			{kind: intLit, pos: 25, val: []byte("25")},
+		}},
+		{name: "closing paren", in: "(a == 1)", want: []*token{
+			{kind: openParen, pos: 0},
+			{kind: ident, pos: 1, val: []byte("a")},
+			{kind: eqOp, pos: 3, val: []byte("==")},
+			{kind: intLit, pos: 6, val: []byte("1")},
+			{kind: closeParen, pos: 7},
+		}},
+		{name: "list", in: `tenant not in ['a', "b"]`, want: []*token{
+			{kind: ident, pos: 0, val: []byte("tenant")},
+			{kind: notOp, pos: 7, val: []byte("not")},
+			{kind: inOp, pos: 11, val: []byte("in")},
+			{kind: openBracket, pos: 14},
+			{kind: stringLit, pos: 15, val: []byte("a"), opened: '\''},
+			{kind: comma, pos: 18},
+			{kind: stringLit, pos: 20, val: []byte("b"), opened: '"'},
+			{kind: closeBracket, pos: 23},
+		}},
+		{name: "list without spaces", in: `userID in[1,2]`, want: []*token{
+			{kind: ident, pos: 0, val: []byte("userID")},
+			{kind: inOp, pos: 7, val: []byte("in")},
+			{kind: openBracket, pos: 9},
+			{kind: intLit, pos: 10, val: []byte("1")},
+			{kind: comma, pos: 11},
+			{kind: intLit, pos: 12, val: []byte("2")},
+			{kind: closeBracket, pos: 13},
+		}},
+		{name: "inclusive", in: "a <= 1 || 2 >=b", want: []*token{
+			{kind: ident, pos: 0, val: []byte("a")},
+			{kind: leOp, pos: 2},
+			{kind: intLit, pos: 5, val: []byte("1")},
+			{kind: orOp, pos: 7, val: []byte("||")},
+			{kind: intLit, pos: 10, val: []byte("2")},
+			{kind: geOp, pos: 12},
+			{kind: ident, pos: 14, val: []byte("b")},
+		}},
+		{name: "separated inclusive", in: "a < = 1", want: []*token{
+			{kind: ident, pos: 0, val: []byte("a")},
+			{kind: ltOp, pos: 2},
+			{kind: eqOp, pos: 4, val: []byte("=")},
+			{kind: intLit, pos: 6, val: []byte("1")},
+		}},
+		{name: "not", in: "!(a == 1) && not (b != 2)", want: []*token{
+			{kind: notOp, pos: 0, val: []byte("not")},
+			{kind: openParen, pos: 1},
+			{kind: ident, pos: 2, val: []byte("a")},
+			{kind: eqOp, pos: 4, val: []byte("==")},
+			{kind: intLit, pos: 7, val: []byte("1")},
+			{kind: closeParen, pos: 8},
+			{kind: andOp, pos: 10, val: []byte("&&")},
+			{kind: notOp, pos: 13, val: []byte("not")},
+			{kind: openParen, pos: 17},
+			{kind: ident, pos: 18, val: []byte("b")},
+			{kind: neOp, pos: 20, val: []byte("!=")},
+			{kind: intLit, pos: 23, val: []byte("2")},
+			{kind: closeParen, pos: 24},
+		}},
+		{name: "text", in: `email endsWith '@example.com'`, want: []*token{
+			{kind: ident, pos: 0, val: []byte("email")},
+			{kind: textOp, pos: 6, val: []byte("endsWith")},
+			{kind: stringLit, pos: 15, val: []byte("@example.com"), opened: '\''},
+		}},
 		{name: "complex string", in: `'some > string \< with \' \" data |= &! @% \\ ()'`, want: []*token{{kind: stringLit, val: []byte(`some > string \< with ' \" data |= &! @% \\ ()`), opened: '\''}}},
 	}
 	for _, tt := range tests {
@@ -123,6 +216,89 @@ func TestParseCondition(t *testing.T) {
 		}, Op: OrOp}},
 		{name: "cond1", in: cond1, want: cond1Exp},
 		{name: "cond2", in: cond2, want: cond2Exp},
+		{name: "bucket", in: `bucket(userID, "seed") < 25`, want: Condition{Fields: []ConditionField{
+			{Op: LtOp, ConditionValue: ConditionValue{Name: "userID", Type: IntType, Value: int64(25)}, Bucket: &Bucket{Seed: "seed"}},
+		}}},
+		{name: "bucket no seed", in: `25 > bucket(userID) && plan == "pro"`, want: Condition{Fields: []ConditionField{
+			{Op: LtOp, ConditionValue: ConditionValue{Name: "userID", Type: IntType, Value: int64(25)}, Bucket: &Bucket{}},
+			{Op: EqOp, ConditionValue: ConditionValue{Name: "plan", Type: StringType, Value: "pro"}},
+		}}},
+		{name: "unknown func", in: `hash(userID) < 25`, wantErr: true},
+		{name: "unterminated bucket", in: `bucket(userID, "seed" < 25`, wantErr: true},
+		{name: "bucket without name", in: `bucket("seed") < 25`, wantErr: true},
+		{name: "in", in: `tenant in ['a', 'b'] && userID not in [1, 2, 3]`, want: Condition{Fields: []ConditionField{
+			{Op: InOp, ConditionValue: ConditionValue{Name: "tenant", Type: StringType, Value: []string{"a", "b"}}},
+			{Op: NotInOp, ConditionValue: ConditionValue{Name: "userID", Type: IntType, Value: []int64{1, 2, 3}}},
+		}}},
+		{name: "in single", in: `ratio in [0.5]`, want: Condition{Fields: []ConditionField{
+			{Op: InOp, ConditionValue: ConditionValue{Name: "ratio", Type: FloatType, Value: []float64{0.5}}},
+		}}},
+		{name: "bucket in", in: `bucket(userID) in [1, 2] || plan == 'pro'`, want: Condition{Op: OrOp, Fields: []ConditionField{
+			{Op: InOp, ConditionValue: ConditionValue{Name: "userID", Type: IntType, Value: []int64{1, 2}}, Bucket: &Bucket{}},
+			{Op: EqOp, ConditionValue: ConditionValue{Name: "plan", Type: StringType, Value: "pro"}},
+		}}},
+		{name: "in without list", in: `tenant in 'a'`, wantErr: true},
+		{name: "list without in", in: `tenant == ['a']`, wantErr: true},
+		{name: "empty list", in: `tenant in []`, wantErr: true},
+		{name: "mixed list", in: `tenant in ['a', 1]`, wantErr: true},
+		{name: "unterminated list", in: `tenant in ['a', 'b'`, wantErr: true},
+		{name: "not without in", in: `tenant not ['a']`, wantErr: true},
+		{name: "in without name", in: `in ['a']`, wantErr: true},
+		{name: "inclusive", in: "version >= 3 && 0.5 >= ratio", want: Condition{Fields: []ConditionField{
+			{Op: GeOp, ConditionValue: ConditionValue{Name: "version", Type: IntType, Value: int64(3)}},
+			{Op: LeOp, ConditionValue: ConditionValue{Name: "ratio", Type: FloatType, Value: 0.5}},
+		}}},
+		{name: "inclusive reversed", in: "3 <= version", want: Condition{Fields: []ConditionField{
+			{Op: GeOp, ConditionValue: ConditionValue{Name: "version", Type: IntType, Value: int64(3)}},
+		}}},
+		{name: "not", in: "!(tenant == 'a' || tenant == 'b') && userID < 10", want: Condition{Conditions: []Condition{
+			{Op: OrOp, Not: true, Fields: []ConditionField{
+				{Op: EqOp, ConditionValue: ConditionValue{Name: "tenant", Type: StringType, Value: "a"}},
+				{Op: EqOp, ConditionValue: ConditionValue{Name: "tenant", Type: StringType, Value: "b"}},
+			}},
+		}, Fields: []ConditionField{
+			{Op: LtOp, ConditionValue: ConditionValue{Name: "userID", Type: IntType, Value: int64(10)}},
+		}}},
+		{name: "not keyword", in: "not(plan == 'pro')", want: Condition{Conditions: []Condition{
+			{Not: true, Fields: []ConditionField{
+				{Op: EqOp, ConditionValue: ConditionValue{Name: "plan", Type: StringType, Value: "pro"}},
+			}},
+		}}},
+		{name: "double not", in: "not (!(plan == 'pro'))", want: Condition{Conditions: []Condition{
+			{Not: true, Conditions: []Condition{
+				{Not: true, Fields: []ConditionField{
+					{Op: EqOp, ConditionValue: ConditionValue{Name: "plan", Type: StringType, Value: "pro"}},
+				}},
+			}},
+		}}},
+		{name: "not without group", in: "not plan == 'pro'", wantErr: true},
+		{name: "unterminated not", in: "!(plan == 'pro'", wantErr: true},
+		{name: "invalid inclusive", in: "version =< 3", wantErr: true},
+		{name: "text", in: `path matches '^/api/v\d+/' && path like "/api/*/users" || email endsWith '@example.com' && email startsWith 'a' && plan contains 'pro'`, want: Condition{Op: OrOp, Conditions: []Condition{
+			{Op: AndOp, Fields: []ConditionField{
+				{Op: MatchesOp, ConditionValue: ConditionValue{Name: "path", Type: StringType, Value: `^/api/v\d+/`}, re: regexp.MustCompile(`^/api/v\d+/`)},
+				{Op: LikeOp, ConditionValue: ConditionValue{Name: "path", Type: StringType, Value: "/api/*/users"}, re: regexp.MustCompile(globRegexp("/api/*/users"))},
+			}},
+			{Op: AndOp, Fields: []ConditionField{
+				{Op: EndsWithOp, ConditionValue: ConditionValue{Name: "email", Type: StringType, Value: "@example.com"}},
+				{Op: StartsWithOp, ConditionValue: ConditionValue{Name: "email", Type: StringType, Value: "a"}},
+				{Op: ContainsOp, ConditionValue: ConditionValue{Name: "plan", Type: StringType, Value: "pro"}},
+			}},
+		}}},
+		{name: "text non-string", in: `userID contains 1`, wantErr: true},
+		{name: "text reversed", in: `'pro' contains plan`, wantErr: true},
+		{name: "text without value", in: `plan contains`, wantErr: true},
+		{name: "semver", in: "appVersion >= 4.12.0 && appVersion in [5.0.0-rc.1, 5.0.0]", want: Condition{Fields: []ConditionField{
+			{Op: GeOp, ConditionValue: ConditionValue{Name: "appVersion", Type: SemverType, Value: "4.12.0"}},
+			{Op: InOp, ConditionValue: ConditionValue{Name: "appVersion", Type: SemverType, Value: []string{"5.0.0-rc.1", "5.0.0"}}},
+		}}},
+		{name: "invalid semver", in: "appVersion >= 4.12.0.1", wantErr: true},
+		{name: "semver leading zero", in: "appVersion >= 4.012.0", wantErr: true},
+		{name: "time", in: "now >= 2021-03-01T09:00:00Z && now < 2021-04-01", want: Condition{Fields: []ConditionField{
+			{Op: GeOp, ConditionValue: ConditionValue{Name: "now", Type: TimeType, Value: time.Date(2021, 3, 1, 9, 0, 0, 0, time.UTC)}},
+			{Op: LtOp, ConditionValue: ConditionValue{Name: "now", Type: TimeType, Value: time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC)}},
+		}}},
+		{name: "invalid time", in: "now < 2021-13-01", wantErr: true},
 	}
 
 	for _, tt := range tests {
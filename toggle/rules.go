@@ -0,0 +1,63 @@
+package toggle
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Rule is a targeting rule of a flag, which overrides the flag value when its
+// condition matches
+type Rule struct {
+	Condition Condition `json:"cond,omitempty"`
+	Expr      string    `json:"expr,omitempty"`
+
+	RawValue string `json:"raw,omitempty"`
+	Value    bool   `json:"value,omitempty"`
+}
+
+func (r *Rule) UnmarshalJSON(d []byte) error {
+	type rule Rule
+
+	var intermediate rule
+	err := json.Unmarshal(d, &intermediate)
+	if err != nil {
+		return err
+	}
+
+	*r = Rule(intermediate)
+
+	if r.Expr != "" && !r.Condition.hasMatchers() {
+		r.Condition, err = ParseCondition(strings.NewReader(r.Expr))
+	}
+	return err
+}
+
+// String returns a human-readable representation of a rule
+func (r Rule) String() string {
+	return fmt.Sprintf("%s => %s", r.Condition, r.RawValue)
+}
+
+// resolve returns the raw and boolean values of the first matching rule,
+// along with its index. The flag values and a -1 index are returned if no rule
+// matches.
+func (f Flag) resolve(values []ConditionValue) (string, bool, int) {
+	for i, r := range f.Rules {
+		if r.Condition.Match(values) {
+			return r.RawValue, r.Value, i
+		}
+	}
+
+	return f.RawValue, f.Value, -1
+}
+
+// resolve is like Flag.resolve, using the compiled rule conditions
+func (e entry) resolve(values []ConditionValue) (string, bool, int) {
+	for i, match := range e.rules {
+		if match(values) {
+			return e.Rules[i].RawValue, e.Rules[i].Value, i
+		}
+	}
+
+	return e.RawValue, e.Value, -1
+}
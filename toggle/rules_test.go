@@ -0,0 +1,82 @@
+package toggle
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	rulesFlag = Flag{Name: "checkout", ServiceName: "serv1", RawValue: "default", Rules: []Rule{
+		{Condition: Condition{Fields: []ConditionField{
+			{ConditionValue: ConditionValue{Name: "tenant", Type: StringType, Value: "beta"}},
+		}}, RawValue: "beta", Value: true},
+		{Condition: Condition{Fields: []ConditionField{
+			{ConditionValue: ConditionValue{Name: "userID", Type: IntType, Value: int64(100)}, Op: LtOp},
+		}}, RawValue: "early", Value: true},
+		{Condition: Condition{Fields: []ConditionField{
+			{ConditionValue: ConditionValue{Name: "userID", Type: IntType, Value: int64(10)}, Op: LtOp},
+		}}, RawValue: "late", Value: true},
+	}}
+
+	globalRulesFlag = Flag{Name: "checkout", RawValue: "global", Value: true, Rules: []Rule{
+		{Condition: Condition{Fields: []ConditionField{
+			{ConditionValue: ConditionValue{Name: "tenant", Type: StringType, Value: "beta"}},
+		}}, RawValue: "global beta", Value: true},
+	}}
+
+	gatedFlag = Flag{Name: "checkout", ServiceName: "serv1", RawValue: "gated", Value: true, Condition: Condition{Fields: []ConditionField{
+		{ConditionValue: ConditionValue{Name: "tenant", Type: StringType, Value: "internal"}},
+	}}}
+)
+
+func TestClient_getFlag_rules(t *testing.T) {
+	tests := []struct {
+		name  string
+		flags []Flag
+		opts  []Option
+		want  string
+	}{
+		{name: "fallthrough", flags: []Flag{rulesFlag}, want: "default"},
+		{name: "first rule", flags: []Flag{rulesFlag}, opts: []Option{ForString("tenant", "beta"), ForInt("userID", 5)}, want: "beta"},
+		{name: "second rule", flags: []Flag{rulesFlag}, opts: []Option{ForInt("userID", 500)}, want: "early"},
+		{name: "third rule", flags: []Flag{rulesFlag}, opts: []Option{ForInt("userID", 50)}, want: "late"},
+		{name: "no rule", flags: []Flag{rulesFlag}, opts: []Option{ForInt("userID", 5)}, want: "default"},
+		{name: "global not requested", flags: []Flag{globalRulesFlag}, opts: []Option{ForString("tenant", "beta")}},
+		{name: "global", flags: []Flag{globalRulesFlag}, opts: []Option{Global, ForString("tenant", "beta")}, want: "global beta"},
+		{name: "service overrides global", flags: []Flag{globalRulesFlag, rulesFlag}, opts: []Option{Global, ForString("tenant", "beta")}, want: "beta"},
+		{name: "service fallthrough overrides global", flags: []Flag{globalRulesFlag, rulesFlag}, opts: []Option{Global, ForString("tenant", "other")}, want: "default"},
+		{name: "failed condition falls through to global", flags: []Flag{gatedFlag, globalRulesFlag}, opts: []Option{Global, ForString("tenant", "beta")}, want: "global beta"},
+		{name: "matched condition", flags: []Flag{globalRulesFlag, gatedFlag}, opts: []Option{Global, ForString("tenant", "internal")}, want: "gated"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New("serv1")
+			c.modifyStore(func() {
+				for _, f := range tt.flags {
+					c.store[f.Name] = append(c.store[f.Name], newEntry(f, ""))
+				}
+			})
+
+			assert.Equal(t, tt.want, c.GetRaw("checkout", tt.opts...))
+		})
+	}
+}
+
+func TestRule_UnmarshalJSON(t *testing.T) {
+	var f Flag
+	err := json.Unmarshal([]byte(`{"name":"checkout","service":"serv1","raw":"default","rules":[`+
+		`{"expr":"tenant == 'beta'","raw":"beta","value":true},`+
+		`{"cond":{"fields":[{"op":2,"name":"userID","type":0,"value":100}]},"raw":"early","value":true},`+
+		`{"expr":"userID < 10","raw":"late","value":true}]}`), &f)
+
+	a := assert.New(t)
+	a.NoError(err)
+
+	f.Rules[0].Expr, f.Rules[2].Expr = "", ""
+	a.Equal(rulesFlag, f)
+
+	err = json.Unmarshal([]byte(`{"name":"checkout","rules":[{"expr":"tenant == ","raw":"beta"}]}`), &f)
+	a.Error(err)
+}
@@ -0,0 +1,167 @@
+package toggle
+
+import "strings"
+
+// semver is a parsed semantic version, as described by https://semver.org.
+// Its parts reference the version string.
+type semver struct {
+	major, minor, patch string
+	// pre holds the dot separated pre-release identifiers
+	pre string
+}
+
+// parseSemver parses a version such as 1.2.3, 1.2.3-beta.1 or 1.2.3+build.5,
+// optionally prefixed with a v. It reports whether the version is valid.
+func parseSemver(s string) (semver, bool) {
+	var v semver
+
+	s = strings.TrimPrefix(s, "v")
+
+	if i := strings.IndexByte(s, '+'); i >= 0 {
+		if !validIdentifiers(s[i+1:], false) {
+			return v, false
+		}
+		s = s[:i]
+	}
+
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		v.pre = s[i+1:]
+		if !validIdentifiers(v.pre, true) {
+			return v, false
+		}
+		s = s[:i]
+	}
+
+	if strings.HasSuffix(s, ".") {
+		return v, false
+	}
+
+	var ok bool
+	for _, part := range []*string{&v.major, &v.minor, &v.patch} {
+		*part, s, ok = nextIdentifier(s)
+		if !ok || !numericIdentifier(*part) || len(*part) > 1 && (*part)[0] == '0' {
+			return v, false
+		}
+	}
+
+	return v, s == ""
+}
+
+// compareSemver returns -1, 0 or 1 if the version a has a lower, the same or a
+// higher precedence than b. Build metadata is ignored, and a pre-release
+// version has a lower precedence than the release.
+func compareSemver(a, b semver) int {
+	for _, parts := range [][2]string{{a.major, b.major}, {a.minor, b.minor}, {a.patch, b.patch}} {
+		if order := compareNumeric(parts[0], parts[1]); order != 0 {
+			return order
+		}
+	}
+
+	switch {
+	case a.pre == b.pre:
+		return 0
+	case a.pre == "":
+		return 1
+	case b.pre == "":
+		return -1
+	}
+
+	// Identifiers are compared in order, with a larger set of identifiers
+	// having a higher precedence when the previous ones are equal
+	apre, bpre := a.pre, b.pre
+	for apre != "" && bpre != "" {
+		var aid, bid string
+		aid, apre, _ = nextIdentifier(apre)
+		bid, bpre, _ = nextIdentifier(bpre)
+
+		if order := compareIdentifier(aid, bid); order != 0 {
+			return order
+		}
+	}
+
+	switch {
+	case apre != "":
+		return 1
+	case bpre != "":
+		return -1
+	}
+	return 0
+}
+
+// compareIdentifier compares pre-release identifiers, numerically when they
+// are both numeric. Numeric identifiers have a lower precedence than others.
+func compareIdentifier(a, b string) int {
+	anum, bnum := numericIdentifier(a), numericIdentifier(b)
+
+	switch {
+	case anum && bnum:
+		return compareNumeric(a, b)
+	case anum:
+		return -1
+	case bnum:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// compareNumeric compares numeric identifiers without leading zeros, which
+// may exceed the integer range
+func compareNumeric(a, b string) int {
+	switch {
+	case len(a) < len(b):
+		return -1
+	case len(a) > len(b):
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// nextIdentifier returns the identifier up to the next dot, and the remaining
+// identifiers. It reports whether the identifier is non-empty.
+func nextIdentifier(s string) (string, string, bool) {
+	id, rest := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		id, rest = s[:i], s[i+1:]
+	}
+
+	return id, rest, id != ""
+}
+
+// validIdentifiers checks that the dot separated identifiers are non-empty and
+// only contain ASCII alphanumerics and hyphens. Numeric pre-release
+// identifiers must not have leading zeros.
+func validIdentifiers(s string, pre bool) bool {
+	if s == "" || strings.HasSuffix(s, ".") {
+		return false
+	}
+
+	for s != "" {
+		var id string
+		var ok bool
+		if id, s, ok = nextIdentifier(s); !ok {
+			return false
+		}
+
+		for _, r := range id {
+			if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r == '-') {
+				return false
+			}
+		}
+
+		if pre && numericIdentifier(id) && len(id) > 1 && id[0] == '0' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func numericIdentifier(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return s != ""
+}
@@ -0,0 +1,82 @@
+package toggle
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseSemver(t *testing.T) {
+	tests := []struct {
+		in    string
+		want  semver
+		valid bool
+	}{
+		{in: "1.2.3", want: semver{major: "1", minor: "2", patch: "3"}, valid: true},
+		{in: "v10.20.30", want: semver{major: "10", minor: "20", patch: "30"}, valid: true},
+		{in: "1.0.0-alpha.1+build.5", want: semver{major: "1", minor: "0", patch: "0", pre: "alpha.1"}, valid: true},
+		{in: "1.0.0+build-5", want: semver{major: "1", minor: "0", patch: "0"}, valid: true},
+		{in: "1.0.0-x-y.0a", want: semver{major: "1", minor: "0", patch: "0", pre: "x-y.0a"}, valid: true},
+		{in: "1.2"},
+		{in: "1.2.3.4"},
+		{in: "1.2.3."},
+		{in: "01.2.3"},
+		{in: "1.2.x"},
+		{in: "1.2.3-"},
+		{in: "1.2.3-beta."},
+		{in: "1.2.3-beta..1"},
+		{in: "1.2.3-01"},
+		{in: "1.2.3-beta_1"},
+		{in: "1.2.3+"},
+		{in: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, valid := parseSemver(tt.in)
+
+			assert.Equal(t, tt.valid, valid)
+			if tt.valid {
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_compareSemver(t *testing.T) {
+	// Ordered by precedence, as in the semver specification
+	ordered := []string{
+		"0.9.99",
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+		"1.2.0",
+		"1.10.0",
+		"2.0.0",
+		"18446744073709551616.0.0",
+	}
+
+	for i, a := range ordered {
+		for j, b := range ordered {
+			va, _ := parseSemver(a)
+			vb, _ := parseSemver(b)
+
+			want := 0
+			if i < j {
+				want = -1
+			} else if i > j {
+				want = 1
+			}
+
+			assert.Equal(t, want, compareSemver(va, vb), "%s <=> %s", a, b)
+		}
+	}
+
+	a, _ := parseSemver("1.0.0+build.1")
+	b, _ := parseSemver("1.0.0+build.2")
+	assert.Equal(t, 0, compareSemver(a, b))
+}
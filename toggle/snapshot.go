@@ -0,0 +1,113 @@
+package toggle
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// snapshot is the on-disk representation of the last known server flags
+type snapshot struct {
+	Service string    `json:"service"`
+	Time    time.Time `json:"time"`
+	Flags   []Flag    `json:"flags"`
+}
+
+// saveSnapshot atomically writes the flags obtained from the server to the
+// snapshot file, if one is configured. Environment and manual flags aren't
+// saved.
+func (c *Client) saveSnapshot() {
+	if c.opts.snapshotPath == "" {
+		return
+	}
+
+	s := snapshot{Service: c.name, Time: time.Now()}
+
+	for _, entries := range c.loadStore() {
+		for _, e := range entries {
+			switch e.source {
+			case SeedSource, PollSource, EventSource, SnapshotSource:
+				s.Flags = append(s.Flags, e.Flag)
+			}
+		}
+	}
+
+	if err := writeSnapshot(c.opts.snapshotPath, s); err != nil {
+		c.opts.log.Println("Error saving flag snapshot:", err)
+	}
+}
+
+// loadSnapshot populates the store with the snapshot flags, unless the
+// snapshot is missing, belongs to another service or is older than the
+// maximum allowed age. Snapshot flags override environment flags with the
+// same name and service. It reports whether a snapshot was loaded.
+func (c *Client) loadSnapshot() (bool, error) {
+	if c.opts.snapshotPath == "" {
+		return false, nil
+	}
+
+	b, err := ioutil.ReadFile(c.opts.snapshotPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("reading flag snapshot: %v", err)
+	}
+
+	var s snapshot
+	if err := json.Unmarshal(b, &s); err != nil {
+		return false, fmt.Errorf("decoding flag snapshot: %v", err)
+	}
+
+	if s.Service != c.name {
+		return false, fmt.Errorf("flag snapshot belongs to service %q", s.Service)
+	}
+
+	if age := time.Since(s.Time); c.opts.snapshotMaxAge > 0 && age > c.opts.snapshotMaxAge {
+		return false, fmt.Errorf("flag snapshot is stale: %s old", age.Round(time.Second))
+	}
+
+	c.modifyStore(func() {
+		for _, f := range s.Flags {
+			c.setEntry(newEntry(f.Normalized(), SnapshotSource))
+		}
+	})
+
+	return true, nil
+}
+
+func writeSnapshot(path string, s snapshot) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("encoding flag snapshot: %v", err)
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("creating temporary snapshot file: %v", err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return fmt.Errorf("writing temporary snapshot file: %v", err)
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("syncing temporary snapshot file: %v", err)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("closing temporary snapshot file: %v", err)
+	}
+
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("replacing snapshot file: %v", err)
+	}
+
+	return nil
+}
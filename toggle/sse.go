@@ -0,0 +1,138 @@
+package toggle
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SSEBus is an EventBus which receives flag events from the stream endpoint of
+// the flags server, using server-sent events. The stream is reconnected when
+// interrupted, and resumed from the last received event.
+type SSEBus struct {
+	url        string
+	httpClient *http.Client
+	retry      time.Duration
+}
+
+// NewSSEBus creates an event bus for the given stream url, such as
+// http://toggles/flags/my-service/stream. The default http client is used if
+// none is given.
+func NewSSEBus(url string, httpClient *http.Client) *SSEBus {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	return &SSEBus{url: url, httpClient: httpClient, retry: time.Second}
+}
+
+func (b *SSEBus) Receiver(ctx context.Context) <-chan Event {
+	ch := make(chan Event)
+	go func() {
+		defer close(ch)
+
+		var lastID string
+		retry := b.retry
+		for {
+			err := b.stream(ctx, ch, &lastID, &retry)
+			if ctx.Err() != nil {
+				return
+			}
+
+			if err == nil {
+				err = io.ErrUnexpectedEOF
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case ch <- Event{Type: ErrorEvent, Error: "receiving flag event stream: " + err.Error()}:
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(retry):
+			}
+		}
+	}()
+
+	return ch
+}
+
+// stream reads the events until the stream ends, keeping track of the last
+// event id and the retry duration requested by the server
+func (b *SSEBus) stream(ctx context.Context, ch chan<- Event, lastID *string, retry *time.Duration) error {
+	r, err := http.NewRequestWithContext(ctx, "GET", b.url, nil)
+	if err != nil {
+		return fmt.Errorf("creating stream request: %v", err)
+	}
+	r.Header.Set("Accept", "text/event-stream")
+	r.Header.Set("Cache-Control", "no-cache")
+	if *lastID != "" {
+		r.Header.Set("Last-Event-ID", *lastID)
+	}
+
+	resp, err := b.httpClient.Do(r)
+	if err != nil {
+		return fmt.Errorf("getting stream response: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("invalid status code for %s: %d (%s)", cleanupURL(r.URL), resp.StatusCode, resp.Status)
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	var id string
+	var data []string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		if line == "" {
+			if id != "" {
+				*lastID = id
+			}
+			if len(data) > 0 {
+				var ev Event
+				if err := json.Unmarshal([]byte(strings.Join(data, "\n")), &ev); err != nil {
+					return fmt.Errorf("decoding event: %v", err)
+				}
+
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case ch <- ev:
+				}
+			}
+
+			id, data = "", nil
+			continue
+		}
+
+		field, value := line, ""
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		}
+
+		switch field {
+		case "id":
+			id = value
+		case "data":
+			data = append(data, value)
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil {
+				*retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package toggle_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/globusdigital/feature-toggles/toggle"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSSEBus_Receiver(t *testing.T) {
+	a := assert.New(t)
+
+	var connections int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		connections++
+		w.Header().Set("Content-Type", "text/event-stream")
+
+		switch connections {
+		case 1:
+			a.Empty(r.Header.Get("Last-Event-ID"))
+			fmt.Fprint(w, "retry: 10\n: comment\n\n")
+			fmt.Fprint(w, "id: 1\nevent: save\ndata: {\"type\":\"save\",\n")
+			fmt.Fprint(w, "data: \"flags\":[{\"name\":\"f1\",\"raw\":\"t\",\"value\":true}]}\n\n")
+			fmt.Fprint(w, "id: 2\nevent: save\ndata: {\"type\":\"save\"")
+		case 2:
+			a.Equal("1", r.Header.Get("Last-Event-ID"))
+			http.Error(w, "error", 500)
+		default:
+			a.Equal("1", r.Header.Get("Last-Event-ID"))
+			fmt.Fprint(w, "id: 3\nevent: delete\ndata: {\"type\":\"delete\",\"flags\":[{\"name\":\"f1\"}]}\n\n")
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		}
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	ch := toggle.NewSSEBus(ts.URL, nil).Receiver(ctx)
+
+	var types []toggle.EventType
+	for ev := range ch {
+		types = append(types, ev.Type)
+		if ev.Type == toggle.SaveEvent {
+			a.Equal([]toggle.Flag{{Name: "f1", RawValue: "t", Value: true}}, ev.Flags)
+		}
+		if ev.Type == toggle.DeleteEvent {
+			cancel()
+		}
+	}
+
+	a.Equal([]toggle.EventType{toggle.SaveEvent, toggle.ErrorEvent, toggle.ErrorEvent, toggle.DeleteEvent}, types)
+	a.NotEqual(context.DeadlineExceeded, ctx.Err())
+}
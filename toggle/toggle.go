@@ -3,6 +3,7 @@ package toggle
 import (
 	"context"
 	"os"
+	"time"
 )
 
 var (
@@ -28,6 +29,16 @@ func Initialize(ctx context.Context, name string, opts ...ClientOption) {
 	}()
 }
 
+// WaitReady calls the DefaultClient.WaitReady method
+func WaitReady(ctx context.Context) error {
+	return DefaultClient.WaitReady(ctx)
+}
+
+// Close calls the DefaultClient.Close method
+func Close() {
+	DefaultClient.Close()
+}
+
 // Get calls the DefaultClient.Get method
 func Get(name string, opts ...Option) bool {
 	return DefaultClient.Get(name, opts...)
@@ -37,3 +48,74 @@ func Get(name string, opts ...Option) bool {
 func GetRaw(name string, opts ...Option) string {
 	return DefaultClient.GetRaw(name, opts...)
 }
+
+// GetInt calls the DefaultClient.GetInt method
+func GetInt(name string, def int64, opts ...Option) int64 {
+	return DefaultClient.GetInt(name, def, opts...)
+}
+
+// GetFloat calls the DefaultClient.GetFloat method
+func GetFloat(name string, def float64, opts ...Option) float64 {
+	return DefaultClient.GetFloat(name, def, opts...)
+}
+
+// GetDuration calls the DefaultClient.GetDuration method
+func GetDuration(name string, def time.Duration, opts ...Option) time.Duration {
+	return DefaultClient.GetDuration(name, def, opts...)
+}
+
+// GetJSON calls the DefaultClient.GetJSON method
+func GetJSON(name string, v interface{}, opts ...Option) {
+	DefaultClient.GetJSON(name, v, opts...)
+}
+
+// GetVariant calls the DefaultClient.Variant method. It is not named Variant,
+// as that is the name of the variant type.
+func GetVariant(name string, opts ...Option) Variant {
+	return DefaultClient.Variant(name, opts...)
+}
+
+// Evaluate calls the DefaultClient.Evaluate method
+func Evaluate(name string, opts ...Option) EvaluationDetail {
+	return DefaultClient.Evaluate(name, opts...)
+}
+
+// GetCtx calls the DefaultClient.GetCtx method
+func GetCtx(ctx context.Context, name string, opts ...Option) bool {
+	return DefaultClient.GetCtx(ctx, name, opts...)
+}
+
+// GetRawCtx calls the DefaultClient.GetRawCtx method
+func GetRawCtx(ctx context.Context, name string, opts ...Option) string {
+	return DefaultClient.GetRawCtx(ctx, name, opts...)
+}
+
+// GetIntCtx calls the DefaultClient.GetIntCtx method
+func GetIntCtx(ctx context.Context, name string, def int64, opts ...Option) int64 {
+	return DefaultClient.GetIntCtx(ctx, name, def, opts...)
+}
+
+// GetFloatCtx calls the DefaultClient.GetFloatCtx method
+func GetFloatCtx(ctx context.Context, name string, def float64, opts ...Option) float64 {
+	return DefaultClient.GetFloatCtx(ctx, name, def, opts...)
+}
+
+// GetDurationCtx calls the DefaultClient.GetDurationCtx method
+func GetDurationCtx(ctx context.Context, name string, def time.Duration, opts ...Option) time.Duration {
+	return DefaultClient.GetDurationCtx(ctx, name, def, opts...)
+}
+
+// GetJSONCtx calls the DefaultClient.GetJSONCtx method
+func GetJSONCtx(ctx context.Context, name string, v interface{}, opts ...Option) {
+	DefaultClient.GetJSONCtx(ctx, name, v, opts...)
+}
+
+// VariantCtx calls the DefaultClient.VariantCtx method
+func VariantCtx(ctx context.Context, name string, opts ...Option) Variant {
+	return DefaultClient.VariantCtx(ctx, name, opts...)
+}
+
+// EvaluateCtx calls the DefaultClient.EvaluateCtx method
+func EvaluateCtx(ctx context.Context, name string, opts ...Option) EvaluationDetail {
+	return DefaultClient.EvaluateCtx(ctx, name, opts...)
+}
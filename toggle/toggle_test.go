@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/globusdigital/feature-toggles/toggle"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestGet(t *testing.T) {
@@ -59,3 +60,64 @@ func TestGet(t *testing.T) {
 		})
 	}
 }
+
+func TestGetTyped(t *testing.T) {
+	for _, s := range typedSeed {
+		parts := strings.SplitN(s, "=", 2)
+		os.Setenv(parts[0], parts[1])
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	toggle.Initialize(ctx, "serv1")
+
+	a := assert.New(t)
+	a.Equal(int64(12), toggle.GetInt("workers", 4))
+	a.Equal(0.25, toggle.GetFloat("ratio", 1))
+	a.Equal(90*time.Second, toggle.GetDuration("timeout", time.Second))
+
+	var limits struct {
+		Max int `json:"max"`
+	}
+	toggle.GetJSON("limits", &limits)
+	a.Equal(10, limits.Max)
+}
+
+func TestGetCtx(t *testing.T) {
+	for _, s := range typedSeed {
+		parts := strings.SplitN(s, "=", 2)
+		os.Setenv(parts[0], parts[1])
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	toggle.Initialize(ctx, "serv1")
+
+	gated := toggle.Flag{Name: "gated", ServiceName: "serv1", RawValue: "7", Value: true}
+	gated.Condition = condition(t, `tenant == "beta"`)
+	button := toggle.NewFlag("button", "serv1", "t")
+	button.Variants, button.VariantBy = []toggle.Variant{
+		{ConditionValue: toggle.ConditionValue{Name: "blue", Type: toggle.StringType, Value: "#00f"}, Weight: 1},
+	}, "userID"
+	toggle.DefaultClient.SetFlags(gated, button)
+
+	ctx = toggle.WithValues(ctx, toggle.ConditionValue{Name: "tenant", Value: "beta"})
+
+	a := assert.New(t)
+	a.True(toggle.GetCtx(ctx, "gated"))
+	a.False(toggle.Get("gated"))
+	a.Equal("7", toggle.GetRawCtx(ctx, "gated"))
+	a.Equal(int64(7), toggle.GetIntCtx(ctx, "gated", 4))
+	a.Equal(int64(4), toggle.GetIntCtx(context.Background(), "gated", 4))
+	a.Equal(7.0, toggle.GetFloatCtx(ctx, "gated", 1))
+	a.Equal(90*time.Second, toggle.GetDurationCtx(ctx, "timeout", time.Second))
+	a.Equal(toggle.MatchedReason, toggle.EvaluateCtx(ctx, "gated").Reason)
+	a.Empty(toggle.VariantCtx(ctx, "gated").Name)
+	a.Equal("blue", toggle.GetVariant("button", toggle.ForInt("userID", 1)).Name)
+
+	var v int
+	toggle.GetJSONCtx(ctx, "gated", &v)
+	a.Equal(7, v)
+}
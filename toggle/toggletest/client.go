@@ -0,0 +1,207 @@
+// Package toggletest provides fake toggle clients for tests, whose flags are
+// set programmatically instead of using the environment or a server.
+package toggletest
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/globusdigital/feature-toggles/toggle"
+)
+
+// Builder builds a fake client with the given flags
+type Builder struct {
+	t           testing.TB
+	serviceName string
+	opts        []toggle.ClientOption
+	flags       []toggle.Flag
+}
+
+// NewClient starts building a fake client for the given service. The client
+// options are applied when building it.
+func NewClient(t testing.TB, serviceName string, opts ...toggle.ClientOption) *Builder {
+	return &Builder{t: t, serviceName: serviceName, opts: opts}
+}
+
+// Set sets a service flag with the given raw value
+func (b *Builder) Set(name, rawValue string) *Builder {
+	return b.Flag(toggle.NewFlag(name, b.serviceName, rawValue))
+}
+
+// SetGlobal sets a global flag with the given raw value
+func (b *Builder) SetGlobal(name, rawValue string) *Builder {
+	return b.Flag(toggle.NewFlag(name, "", rawValue))
+}
+
+// SetWhen sets a service flag with the given raw value, which only matches
+// when the condition expression does. The test fails if the expression is
+// invalid.
+func (b *Builder) SetWhen(name, rawValue, expr string) *Builder {
+	b.t.Helper()
+
+	f := toggle.NewFlag(name, b.serviceName, rawValue)
+	f.Condition = parseCondition(b.t, expr)
+	f.Expr = expr
+
+	return b.Flag(f)
+}
+
+// SetVariants sets a service flag with the given weighted variants, which are
+// assigned using the named condition value
+func (b *Builder) SetVariants(name, variantBy string, variants ...toggle.Variant) *Builder {
+	f := toggle.NewFlag(name, b.serviceName, "t")
+	f.Variants, f.VariantBy = variants, variantBy
+
+	return b.Flag(f)
+}
+
+// Flag sets arbitrary flags. Flags for other services are ignored.
+func (b *Builder) Flag(flags ...toggle.Flag) *Builder {
+	b.flags = append(b.flags, flags...)
+	return b
+}
+
+// Build creates the client. The test fails if any of the flags are invalid.
+func (b *Builder) Build() *Client {
+	b.t.Helper()
+
+	for _, f := range b.flags {
+		if err := f.Validate(); err != nil {
+			b.t.Fatalf("Invalid flag %s: %v", f, err)
+		}
+	}
+
+	c := &Client{t: b.t, serviceName: b.serviceName}
+	opts := append(append([]toggle.ClientOption{}, b.opts...), toggle.WithEvaluationListener(c.record))
+
+	c.Client = toggle.New(b.serviceName, opts...)
+	c.SetFlags(b.flags...)
+
+	return c
+}
+
+// Client is a fake toggle client, which records the flag evaluations
+type Client struct {
+	*toggle.Client
+	t           testing.TB
+	serviceName string
+
+	mu          sync.Mutex
+	evaluations []toggle.EvaluationDetail
+}
+
+// Override sets the service flag with the given raw value until the test and
+// its subtests complete
+func (c *Client) Override(name, rawValue string) {
+	c.OverrideFlag(toggle.NewFlag(name, c.serviceName, rawValue))
+}
+
+// OverrideFlag sets the flags until the test and its subtests complete
+func (c *Client) OverrideFlag(flags ...toggle.Flag) {
+	c.OverrideFlagT(c.t, flags...)
+}
+
+// OverrideFlagT sets the flags until the given test completes, which is
+// useful for overrides within subtests
+func (c *Client) OverrideFlagT(t testing.TB, flags ...toggle.Flag) {
+	t.Cleanup(c.Client.Override(flags...))
+}
+
+// SetDefault makes the client the toggle.DefaultClient until the test
+// completes. As the default client is global, tests using it can't run in
+// parallel.
+func (c *Client) SetDefault() {
+	previous := toggle.DefaultClient
+	toggle.DefaultClient = c.Client
+
+	c.t.Cleanup(func() {
+		toggle.DefaultClient = previous
+	})
+}
+
+// Evaluations returns the details of the recorded flag evaluations
+func (c *Client) Evaluations() []toggle.EvaluationDetail {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]toggle.EvaluationDetail(nil), c.evaluations...)
+}
+
+// Evaluated returns the number of times the named flag was evaluated
+func (c *Client) Evaluated(name string) int {
+	name = toggle.NormalizeName(name)
+
+	var count int
+	for _, d := range c.Evaluations() {
+		if d.Name == name {
+			count++
+		}
+	}
+
+	return count
+}
+
+// ResetEvaluations discards the recorded flag evaluations
+func (c *Client) ResetEvaluations() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.evaluations = nil
+}
+
+// AssertEvaluated fails the test if any of the named flags weren't evaluated
+func (c *Client) AssertEvaluated(names ...string) bool {
+	c.t.Helper()
+
+	var missing []string
+	for _, name := range names {
+		if c.Evaluated(name) == 0 {
+			missing = append(missing, name)
+		}
+	}
+
+	if len(missing) > 0 {
+		c.t.Errorf("Expected flags to be evaluated: %s", strings.Join(missing, ", "))
+		return false
+	}
+
+	return true
+}
+
+// AssertNotEvaluated fails the test if any of the named flags were evaluated
+func (c *Client) AssertNotEvaluated(names ...string) bool {
+	c.t.Helper()
+
+	var evaluated []string
+	for _, name := range names {
+		if c.Evaluated(name) > 0 {
+			evaluated = append(evaluated, name)
+		}
+	}
+
+	if len(evaluated) > 0 {
+		c.t.Errorf("Expected flags not to be evaluated: %s", strings.Join(evaluated, ", "))
+		return false
+	}
+
+	return true
+}
+
+func (c *Client) record(d toggle.EvaluationDetail) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.evaluations = append(c.evaluations, d)
+}
+
+func parseCondition(t testing.TB, expr string) toggle.Condition {
+	t.Helper()
+
+	cond, err := toggle.ParseCondition(strings.NewReader(expr))
+	if err != nil {
+		t.Fatalf("Invalid condition %q: %v", expr, err)
+	}
+
+	return cond
+}
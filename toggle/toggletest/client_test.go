@@ -0,0 +1,111 @@
+package toggletest_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/globusdigital/feature-toggles/toggle"
+	"github.com/globusdigital/feature-toggles/toggle/toggletest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuilder(t *testing.T) {
+	t.Parallel()
+
+	c := toggletest.NewClient(t, "serv1", toggle.For(toggle.ConditionValue{Name: "tenant", Value: "beta"})).
+		Set("checkout", "yes").
+		SetGlobal("cache", "1").
+		SetWhen("beta", "t", `tenant == "beta"`).
+		SetWhen("alpha", "t", `tenant == "alpha"`).
+		SetVariants("button", "userID",
+			toggle.Variant{ConditionValue: toggle.ConditionValue{Name: "blue", Type: toggle.StringType, Value: "#00f"}, Weight: 1},
+		).
+		Flag(toggle.NewFlag("other", "serv2", "t")).
+		Build()
+
+	a := assert.New(t)
+	a.True(c.Get("checkout"))
+	a.Equal("yes", c.GetRaw("Checkout"))
+	a.False(c.Get("cache"))
+	a.True(c.Get("cache", toggle.Global))
+	a.True(c.Get("beta"))
+	a.False(c.Get("alpha"))
+	a.Equal("blue", c.Variant("button", toggle.ForInt("userID", 1)).Name)
+	a.False(c.Get("other"))
+	a.Equal(toggle.ManualSource, c.Evaluate("checkout").Source)
+}
+
+func TestClient_Override(t *testing.T) {
+	t.Parallel()
+
+	c := toggletest.NewClient(t, "serv1").Set("checkout", "f").Build()
+
+	t.Run("overridden", func(t *testing.T) {
+		c.OverrideFlagT(t, toggle.NewFlag("checkout", "serv1", "t"), toggle.NewFlag("cache", "", "t"))
+
+		assert.True(t, c.Get("checkout"))
+		assert.True(t, c.Get("cache", toggle.Global))
+	})
+
+	t.Run("restored", func(t *testing.T) {
+		assert.False(t, c.Get("checkout"))
+		assert.False(t, c.Get("cache", toggle.Global))
+	})
+
+	c.Override("checkout", "t")
+	assert.True(t, c.Get("checkout"))
+}
+
+func TestClient_Evaluations(t *testing.T) {
+	t.Parallel()
+
+	c := toggletest.NewClient(t, "serv1").Set("checkout", "t").Build()
+	c.Get("checkout")
+	c.GetRaw("Checkout")
+	c.GetInt("missing", 0)
+
+	a := assert.New(t)
+	a.Equal(2, c.Evaluated("checkout"))
+	a.Equal(1, c.Evaluated("missing"))
+	a.Equal(0, c.Evaluated("cache"))
+	a.True(c.AssertEvaluated("checkout", "missing"))
+	a.True(c.AssertNotEvaluated("cache"))
+
+	evaluations := c.Evaluations()
+	a.Len(evaluations, 3)
+	a.Equal(toggle.MatchedReason, evaluations[0].Reason)
+	a.Equal(toggle.NotFoundReason, evaluations[2].Reason)
+
+	c.ResetEvaluations()
+	a.Empty(c.Evaluations())
+
+	ft := &fakeT{TB: t}
+	c = toggletest.NewClient(ft, "serv1").Build()
+	a.False(c.AssertEvaluated("checkout"))
+	a.Equal([]string{"Expected flags to be evaluated: checkout"}, ft.errors)
+}
+
+func TestClient_SetDefault(t *testing.T) {
+	previous := toggle.DefaultClient
+
+	t.Run("default", func(t *testing.T) {
+		c := toggletest.NewClient(t, "serv1").Set("checkout", "t").Build()
+		c.SetDefault()
+
+		assert.True(t, toggle.Get("checkout"))
+		c.AssertEvaluated("checkout")
+	})
+
+	assert.Equal(t, previous, toggle.DefaultClient)
+}
+
+type fakeT struct {
+	testing.TB
+	errors []string
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
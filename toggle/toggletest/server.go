@@ -0,0 +1,244 @@
+package toggletest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/globusdigital/feature-toggles/api"
+	"github.com/globusdigital/feature-toggles/storage"
+	"github.com/globusdigital/feature-toggles/toggle"
+)
+
+// Server is a toggle server for integration tests, running the flags API with
+// an in-memory store and an in-process event bus. Failures can be injected to
+// test how clients handle them.
+type Server struct {
+	*httptest.Server
+
+	t       testing.TB
+	path    string
+	handler http.Handler
+	bus     *Bus
+
+	mu       sync.Mutex
+	latency  time.Duration
+	failures int
+	requests []string
+}
+
+// NewServer starts a toggle server, which is closed when the test completes
+func NewServer(t testing.TB) *Server {
+	s := &Server{t: t, path: "/flags", bus: &Bus{}}
+	s.handler = api.Handler(s.path, storage.NewMem(), sender{s.bus})
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// Env returns the environment variable which configures a client to use the
+// server
+func (s *Server) Env() string {
+	return "FEATURE__GLOBAL__" + toggle.ServerAddressFlag + "=" + s.URL
+}
+
+// EventBus returns the event bus which receives the server flag events
+func (s *Server) EventBus() *Bus {
+	return s.bus
+}
+
+// Client creates a client configured to use the server and its event bus. The
+// client still has to be connected.
+func (s *Server) Client(serviceName string, opts ...toggle.ClientOption) *toggle.Client {
+	c := toggle.New(serviceName, append([]toggle.ClientOption{toggle.WithEventBus(s.bus)}, opts...)...)
+	c.ParseEnv([]string{s.Env()})
+
+	return c
+}
+
+// Save saves the flags as if they were posted to the API, sending them to the
+// event bus and the event streams. Injected failures don't apply.
+func (s *Server) Save(flags ...toggle.Flag) {
+	s.t.Helper()
+	s.send("POST", flags)
+}
+
+// Delete deletes the flags as if they were deleted using the API, sending them
+// to the event bus and the event streams. Injected failures don't apply.
+func (s *Server) Delete(flags ...toggle.Flag) {
+	s.t.Helper()
+	s.send("DELETE", flags)
+}
+
+// SetLatency delays the responses to all subsequent requests
+func (s *Server) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.latency = d
+}
+
+// FailRequests answers the next n requests with an internal server error. A
+// negative n fails all requests, and 0 stops failing them.
+func (s *Server) FailRequests(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures = n
+}
+
+// DropEvents drops the next n events sent to the event bus. A negative n drops
+// all events, and 0 stops dropping them. The event streams are not affected.
+func (s *Server) DropEvents(n int) {
+	s.bus.drop(n)
+}
+
+// Requests returns the method and path of the received requests, such as
+// "GET /flags/my-service"
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.requests...)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, r.Method+" "+strings.TrimSuffix(r.URL.Path, "/"))
+	latency, fail := s.latency, s.failures != 0
+	if s.failures > 0 {
+		s.failures--
+	}
+	s.mu.Unlock()
+
+	if latency > 0 {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(latency):
+		}
+	}
+
+	if fail {
+		http.Error(w, "injected failure", http.StatusInternalServerError)
+		return
+	}
+
+	s.handler.ServeHTTP(w, r)
+}
+
+// send sends the flags to the API, grouped by service
+func (s *Server) send(method string, flags []toggle.Flag) {
+	s.t.Helper()
+
+	services := map[string][]toggle.Flag{}
+	for _, f := range flags {
+		f = f.Normalized()
+		services[f.ServiceName] = append(services[f.ServiceName], f)
+	}
+
+	for serviceName, flags := range services {
+		b, err := json.Marshal(flags)
+		if err != nil {
+			s.t.Fatalf("Error encoding flags: %v", err)
+		}
+
+		// Global flags are accepted for any service
+		if serviceName == "" {
+			serviceName = "global"
+		}
+
+		w, r := httptest.NewRecorder(), httptest.NewRequest(method, s.path+"/"+serviceName, strings.NewReader(string(b)))
+		s.handler.ServeHTTP(w, r)
+
+		if w.Code != http.StatusNoContent {
+			s.t.Fatalf("Error sending flags: %d %s", w.Code, strings.TrimSpace(w.Body.String()))
+		}
+	}
+}
+
+// sender hides the receiver of the bus from the API handler, so that dropped
+// bus events don't affect the event streams
+type sender struct {
+	api.EventBus
+}
+
+// Bus is an in-process event bus, which relays the sent events to all of its
+// receivers
+type Bus struct {
+	mu        sync.Mutex
+	receivers map[chan toggle.Event]struct{}
+	dropCount int
+}
+
+// busBufferSize is the number of events a receiver may lag behind before
+// events are dropped
+const busBufferSize = 256
+
+func (b *Bus) Send(ctx context.Context, event toggle.Event) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.dropCount != 0 {
+		if b.dropCount > 0 {
+			b.dropCount--
+		}
+		return nil
+	}
+
+	for ch := range b.receivers {
+		// Receivers get their own flags, which they may modify
+		ev := event
+		ev.Flags = append([]toggle.Flag(nil), event.Flags...)
+
+		select {
+		case ch <- ev:
+		default:
+			return errors.New("event receiver is full")
+		}
+	}
+
+	return nil
+}
+
+func (b *Bus) Receiver(ctx context.Context) <-chan toggle.Event {
+	ch := make(chan toggle.Event, busBufferSize)
+
+	b.mu.Lock()
+	if b.receivers == nil {
+		b.receivers = map[chan toggle.Event]struct{}{}
+	}
+	b.receivers[ch] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		delete(b.receivers, ch)
+		close(ch)
+	}()
+
+	return ch
+}
+
+func (b *Bus) drop(n int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.dropCount = n
+}
@@ -0,0 +1,153 @@
+package toggletest_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/globusdigital/feature-toggles/toggle"
+	"github.com/globusdigital/feature-toggles/toggle/toggletest"
+	"github.com/stretchr/testify/assert"
+)
+
+func connect(t *testing.T, c *toggle.Client) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	c.Connect(ctx)
+
+	ctx, cancel = context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	if err := c.WaitReady(ctx); err != nil {
+		t.Fatalf("Error waiting for the client: %v", err)
+	}
+}
+
+func changes(c *toggle.Client, name string) <-chan string {
+	ch := make(chan string, 10)
+	c.OnChange(name, func(old, new toggle.Flag) {
+		ch <- new.RawValue
+	})
+
+	return ch
+}
+
+func receive(t *testing.T, ch <-chan string, timeout time.Duration) (string, bool) {
+	select {
+	case v := <-ch:
+		return v, true
+	case <-time.After(timeout):
+		return "", false
+	}
+}
+
+func TestServer_events(t *testing.T) {
+	t.Parallel()
+
+	s := toggletest.NewServer(t)
+	s.Save(toggle.NewFlag("checkout", "serv1", "f"))
+
+	c := s.Client("serv1")
+	connect(t, c)
+
+	a := assert.New(t)
+	a.False(c.Get("checkout"))
+
+	checkout := changes(c, "checkout")
+	s.Save(toggle.NewFlag("checkout", "serv1", "t"), toggle.NewFlag("cache", "", "t"), toggle.NewFlag("checkout", "serv2", "1"))
+	v, ok := receive(t, checkout, time.Second)
+	a.True(ok)
+	a.Equal("t", v)
+	a.True(c.Get("cache", toggle.Global))
+
+	s.DropEvents(1)
+	s.Save(toggle.NewFlag("checkout", "serv1", "dropped"))
+	_, ok = receive(t, checkout, 100*time.Millisecond)
+	a.False(ok, "the event is dropped")
+
+	s.Delete(toggle.NewFlag("checkout", "serv1", ""))
+	v, ok = receive(t, checkout, time.Second)
+	a.True(ok)
+	a.Equal("", v)
+}
+
+func TestServer_events_services(t *testing.T) {
+	t.Parallel()
+
+	s := toggletest.NewServer(t)
+
+	c1, c2 := s.Client("serv1"), s.Client("serv2")
+	connect(t, c1)
+	connect(t, c2)
+
+	a := assert.New(t)
+	checkout1, checkout2 := changes(c1, "checkout"), changes(c2, "checkout")
+
+	for _, raw := range []string{"1", "2", "3"} {
+		a.NoError(s.EventBus().Send(context.Background(), toggle.Event{Type: toggle.SaveEvent, Flags: []toggle.Flag{
+			toggle.NewFlag("checkout", "serv1", "serv1 "+raw),
+			toggle.NewFlag("checkout", "serv2", "serv2 "+raw),
+		}}))
+
+		v, ok := receive(t, checkout1, time.Second)
+		a.True(ok)
+		a.Equal("serv1 "+raw, v)
+
+		v, ok = receive(t, checkout2, time.Second)
+		a.True(ok)
+		a.Equal("serv2 "+raw, v)
+	}
+}
+
+func TestServer_stream(t *testing.T) {
+	t.Parallel()
+
+	s := toggletest.NewServer(t)
+	s.DropEvents(-1)
+
+	c := toggle.New("serv1", toggle.WithServerEvents())
+	c.ParseEnv([]string{s.Env()})
+	connect(t, c)
+
+	checkout := changes(c, "checkout")
+
+	// Wait for the stream to be established
+	time.Sleep(100 * time.Millisecond)
+
+	s.Save(toggle.NewFlag("checkout", "serv1", "t"))
+	v, ok := receive(t, checkout, time.Second)
+	assert.True(t, ok)
+	assert.Equal(t, "t", v)
+}
+
+func TestServer_failures(t *testing.T) {
+	t.Parallel()
+
+	s := toggletest.NewServer(t)
+	s.Save(toggle.NewFlag("checkout", "serv1", "t"))
+	s.FailRequests(2)
+
+	c := s.Client("serv1", toggle.WithBackoff(toggle.Backoff{Initial: 10 * time.Millisecond}))
+	connect(t, c)
+
+	a := assert.New(t)
+	a.True(c.Get("checkout"))
+	a.Equal([]string{"POST /flags/serv1/initial", "POST /flags/serv1/initial", "POST /flags/serv1/initial"}, s.Requests())
+
+	s.FailRequests(-1)
+	resp, err := http.Get(s.URL + "/flags/serv1")
+	a.NoError(err)
+	resp.Body.Close()
+	a.Equal(http.StatusInternalServerError, resp.StatusCode)
+
+	s.FailRequests(0)
+	s.SetLatency(100 * time.Millisecond)
+	start := time.Now()
+	resp, err = http.Get(s.URL + "/flags/serv1")
+	a.NoError(err)
+	resp.Body.Close()
+	a.Equal(http.StatusOK, resp.StatusCode)
+	a.True(time.Since(start) >= 100*time.Millisecond)
+}
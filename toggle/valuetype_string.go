@@ -12,11 +12,13 @@ func _() {
 	_ = x[FloatType-1]
 	_ = x[BoolType-2]
 	_ = x[StringType-3]
+	_ = x[SemverType-4]
+	_ = x[TimeType-5]
 }
 
-const _ValueType_name = "intfloatboolstring"
+const _ValueType_name = "intfloatboolstringsemvertime"
 
-var _ValueType_index = [...]uint8{0, 3, 8, 12, 18}
+var _ValueType_index = [...]uint8{0, 3, 8, 12, 18, 24, 28}
 
 func (i ValueType) String() string {
 	if i < 0 || i >= ValueType(len(_ValueType_index)-1) {
@@ -51,12 +53,21 @@ func _() {
 	_ = x[NeOp-1]
 	_ = x[LtOp-2]
 	_ = x[GtOp-3]
-	_ = x[invalidFieldOp-4]
+	_ = x[InOp-4]
+	_ = x[NotInOp-5]
+	_ = x[LeOp-6]
+	_ = x[GeOp-7]
+	_ = x[MatchesOp-8]
+	_ = x[LikeOp-9]
+	_ = x[StartsWithOp-10]
+	_ = x[EndsWithOp-11]
+	_ = x[ContainsOp-12]
+	_ = x[invalidFieldOp-13]
 }
 
-const _FieldOp_name = "=!=<>err"
+const _FieldOp_name = "=!=<>innot in<=>=matcheslikestartsWithendsWithcontainserr"
 
-var _FieldOp_index = [...]uint8{0, 1, 3, 4, 5, 8}
+var _FieldOp_index = [...]uint8{0, 1, 3, 4, 5, 7, 13, 15, 17, 24, 28, 38, 46, 54, 57}
 
 func (i FieldOp) String() string {
 	if i < 0 || i >= FieldOp(len(_FieldOp_index)-1) {
@@ -73,19 +84,29 @@ func _() {
 	_ = x[floatLit-2]
 	_ = x[boolLit-3]
 	_ = x[stringLit-4]
-	_ = x[andOp-5]
-	_ = x[orOp-6]
-	_ = x[eqOp-7]
-	_ = x[neOp-8]
-	_ = x[ltOp-9]
-	_ = x[gtOp-10]
-	_ = x[openParen-11]
-	_ = x[closeParen-12]
+	_ = x[semverLit-5]
+	_ = x[timeLit-6]
+	_ = x[andOp-7]
+	_ = x[orOp-8]
+	_ = x[eqOp-9]
+	_ = x[neOp-10]
+	_ = x[ltOp-11]
+	_ = x[gtOp-12]
+	_ = x[leOp-13]
+	_ = x[geOp-14]
+	_ = x[inOp-15]
+	_ = x[notOp-16]
+	_ = x[textOp-17]
+	_ = x[openParen-18]
+	_ = x[closeParen-19]
+	_ = x[comma-20]
+	_ = x[openBracket-21]
+	_ = x[closeBracket-22]
 }
 
-const _kind_name = "identifierintegerfloatbooleanstring&& operator|| operator== operator!= operator< operator> operator()"
+const _kind_name = "identifierintegerfloatbooleanstringsemantic versiontime&& operator|| operator== operator!= operator< operator> operator<= operator>= operatorin operatornot operatortext operator(),[]"
 
-var _kind_index = [...]uint8{0, 10, 17, 22, 29, 35, 46, 57, 68, 79, 89, 99, 100, 101}
+var _kind_index = [...]uint8{0, 10, 17, 22, 29, 35, 51, 55, 66, 77, 88, 99, 109, 119, 130, 141, 152, 164, 177, 178, 179, 180, 181, 182}
 
 func (i kind) String() string {
 	if i < 0 || i >= kind(len(_kind_index)-1) {
@@ -0,0 +1,101 @@
+package toggle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Variant is a named and weighted typed payload of a multivariate flag
+type Variant struct {
+	ConditionValue
+	Weight int `json:"weight,omitempty"`
+}
+
+func (v *Variant) UnmarshalJSON(b []byte) error {
+	type variant Variant
+	var val variant
+	if err := json.Unmarshal(b, &val); err != nil {
+		return err
+	}
+
+	val.Value = decodedValue(val.Type, val.Value)
+
+	*v = Variant(val)
+
+	return nil
+}
+
+// String returns a human-readable representation of a variant
+func (v Variant) String() string {
+	return fmt.Sprintf("%s:%d %s(%v)", v.Name, v.Weight, v.Type, v.Value)
+}
+
+// Validate checks if the variant payload and weight are valid
+func (v Variant) Validate() error {
+	if v.Name == "" {
+		return errors.New("empty variant name")
+	}
+
+	if v.Weight < 0 {
+		return fmt.Errorf("invalid variant %s weight %d", v.Name, v.Weight)
+	}
+
+	if err := v.ConditionValue.Validate(); err != nil {
+		return fmt.Errorf("invalid variant %s: %v", v.Name, err)
+	}
+
+	return nil
+}
+
+// variant deterministically assigns one of the flag variants based on the
+// given condition values. If the flag specifies a VariantBy value name, only
+// that value is used for the assignment and no variant is assigned when it is
+// missing. Otherwise all given values are used.
+func (f Flag) variant(values []ConditionValue) Variant {
+	var total int
+	for _, v := range f.Variants {
+		total += v.Weight
+	}
+
+	if total <= 0 {
+		return Variant{}
+	}
+
+	key, ok := variantKey(f.VariantBy, values)
+	if !ok {
+		return Variant{}
+	}
+
+	bucket := int(hashBucket(f.Name, key, uint64(total)))
+	for _, v := range f.Variants {
+		if bucket < v.Weight {
+			return v
+		}
+		bucket -= v.Weight
+	}
+
+	return Variant{}
+}
+
+func variantKey(by string, values []ConditionValue) (string, bool) {
+	if by != "" {
+		for _, v := range values {
+			if v.Name == by {
+				return v.raw(), true
+			}
+		}
+
+		return "", false
+	}
+
+	pairs := make([]string, 0, len(values))
+	for _, v := range values {
+		pairs = append(pairs, v.Name+"="+v.raw())
+	}
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, "&"), true
+}
@@ -0,0 +1,116 @@
+package toggle
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var variantFlag = Flag{Name: "checkout.button", ServiceName: "serv1", RawValue: "t", Value: true, Variants: []Variant{
+	{ConditionValue: ConditionValue{Name: "blue", Type: StringType, Value: "#0000ff"}, Weight: 50},
+	{ConditionValue: ConditionValue{Name: "green", Type: StringType, Value: "#00ff00"}, Weight: 30},
+	{ConditionValue: ConditionValue{Name: "big", Type: IntType, Value: int64(42)}, Weight: 20},
+}, VariantBy: "userID"}
+
+var (
+	windowStart = time.Date(2021, 3, 1, 9, 0, 0, 0, time.UTC)
+	windowEnd   = time.Date(2021, 3, 1, 17, 0, 0, 0, time.UTC)
+)
+
+func TestFlag_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		flag    Flag
+		wantErr bool
+	}{
+		{name: "empty"},
+		{name: "variants", flag: variantFlag},
+		{name: "invalid condition", flag: Flag{Condition: Condition{Fields: []ConditionField{
+			{ConditionValue: ConditionValue{Type: IntType, Value: float64(42)}},
+		}}}, wantErr: true},
+		{name: "empty variant name", flag: Flag{Variants: []Variant{
+			{ConditionValue: ConditionValue{Type: StringType, Value: "a"}, Weight: 1},
+		}}, wantErr: true},
+		{name: "invalid variant payload", flag: Flag{Variants: []Variant{
+			{ConditionValue: ConditionValue{Name: "a", Type: IntType, Value: "a"}, Weight: 1},
+		}}, wantErr: true},
+		{name: "negative weight", flag: Flag{Variants: []Variant{
+			{ConditionValue: ConditionValue{Name: "a", Type: StringType, Value: "a"}, Weight: 2},
+			{ConditionValue: ConditionValue{Name: "b", Type: StringType, Value: "b"}, Weight: -1},
+		}}, wantErr: true},
+		{name: "duplicate variant", flag: Flag{Variants: []Variant{
+			{ConditionValue: ConditionValue{Name: "a", Type: StringType, Value: "a"}, Weight: 1},
+			{ConditionValue: ConditionValue{Name: "a", Type: StringType, Value: "b"}, Weight: 1},
+		}}, wantErr: true},
+		{name: "zero weights", flag: Flag{Variants: []Variant{
+			{ConditionValue: ConditionValue{Name: "a", Type: StringType, Value: "a"}},
+		}}, wantErr: true},
+		{name: "activation window", flag: Flag{ActiveFrom: &windowStart, ActiveUntil: &windowEnd}},
+		{name: "open activation window", flag: Flag{ActiveUntil: &windowEnd}},
+		{name: "inverted activation window", flag: Flag{ActiveFrom: &windowEnd, ActiveUntil: &windowStart}, wantErr: true},
+		{name: "empty activation window", flag: Flag{ActiveFrom: &windowStart, ActiveUntil: &windowStart}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.flag.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Flag.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClient_Variant(t *testing.T) {
+	tests := []struct {
+		name  string
+		flags []Flag
+		opts  []Option
+		want  string
+	}{
+		{name: "missing flag", opts: []Option{ForInt("userID", 1)}},
+		{name: "no variants", flags: []Flag{{Name: "checkout.button", ServiceName: "serv1", RawValue: "t", Value: true}}, opts: []Option{ForInt("userID", 1)}},
+		{name: "missing variant value", flags: []Flag{variantFlag}, opts: []Option{ForString("tenant", "t1")}},
+		{name: "user 1", flags: []Flag{variantFlag}, opts: []Option{ForInt("userID", 1)}, want: "blue"},
+		{name: "user 2", flags: []Flag{variantFlag}, opts: []Option{ForInt("userID", 2), ForString("tenant", "t1")}, want: "green"},
+		{name: "user 6", flags: []Flag{variantFlag}, opts: []Option{ForInt("userID", 6)}, want: "big"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New("serv1")
+			c.modifyStore(func() {
+				for _, f := range tt.flags {
+					c.store[f.Name] = append(c.store[f.Name], newEntry(f, ""))
+				}
+			})
+
+			got := c.Variant("checkout.button", tt.opts...)
+			assert.Equal(t, tt.want, got.Name)
+			assert.Equal(t, got, c.Variant("checkout.button", tt.opts...))
+		})
+	}
+}
+
+func TestFlag_variant_distribution(t *testing.T) {
+	counts := map[string]int{}
+	for i := int64(0); i < 10000; i++ {
+		counts[variantFlag.variant([]ConditionValue{{Name: "userID", Type: IntType, Value: i}}).Name]++
+	}
+
+	a := assert.New(t)
+	a.InDelta(5000, counts["blue"], 200)
+	a.InDelta(3000, counts["green"], 200)
+	a.InDelta(2000, counts["big"], 200)
+}
+
+func TestVariant_UnmarshalJSON(t *testing.T) {
+	var f Flag
+	err := json.Unmarshal([]byte(`{"name":"checkout.button","service":"serv1","raw":"t","value":true,"variants":[`+
+		`{"name":"blue","type":3,"value":"#0000ff","weight":50},`+
+		`{"name":"green","type":3,"value":"#00ff00","weight":30},`+
+		`{"name":"big","type":0,"value":42,"weight":20}],"variantBy":"userID"}`), &f)
+
+	a := assert.New(t)
+	a.NoError(err)
+	a.Equal(variantFlag, f)
+}